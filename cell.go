@@ -0,0 +1,299 @@
+package datadiff
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BytesEncoding selects how []byte cells are rendered in the diff table.
+type BytesEncoding int
+
+const (
+	// BytesHex renders []byte values as lower-case hexadecimal.
+	BytesHex BytesEncoding = iota
+
+	// BytesBase64 renders []byte values as standard base64.
+	BytesBase64
+)
+
+// cellFormatter renders cell values for the diff table. The zero value
+// uses the default layouts.
+type cellFormatter struct {
+	timeLayout string        // layout for time.Time; "" means RFC 3339 with nanoseconds
+	bytes      BytesEncoding // encoding for []byte
+
+	formatters []typedFormatter // user-registered, in registration order
+}
+
+// typedFormatter is a user-supplied formatting function for values of typ.
+type typedFormatter struct {
+	typ    reflect.Type
+	format func(v any) string
+}
+
+// newCellFormatter returns the cell formatter configured by opts.
+func newCellFormatter(opts options) cellFormatter {
+	return cellFormatter{
+		timeLayout: opts.timeLayout,
+		bytes:      opts.bytesEncoding,
+		formatters: opts.formatters,
+	}
+}
+
+// maxFormatDepth bounds how deeply [cellFormatter.format] descends into
+// slices, arrays, maps, structs and pointers, so that cyclic values
+// terminate.
+const maxFormatDepth = 8
+
+// format renders v. In order of precedence it uses: a registered formatter
+// for v's type, the time layout for time.Time, the Error or String
+// method, the bytes encoding for byte slices, and fmt's %v. Pointers
+// without such a method are dereferenced, so that they show their value
+// rather than an address, and the elements of slices, arrays, maps and
+// structs are rendered with the same rules, in the layout %v uses.
+func (f cellFormatter) format(v any) string {
+	return f.formatDepth(v, 0)
+}
+
+func (f cellFormatter) formatDepth(v any, depth int) string {
+	if v == nil {
+		return "<nil>"
+	}
+
+	if format := f.formatterFor(reflect.TypeOf(v)); format != nil {
+		return format(v)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return "<nil>"
+		}
+		if !hasPointerMethods(rv.Type()) {
+			if depth > maxFormatDepth {
+				return ellipsis
+			}
+			return f.formatDepth(rv.Elem().Interface(), depth+1)
+		}
+	}
+
+	switch v := v.(type) {
+	case time.Time:
+		layout := f.timeLayout
+		if layout == "" {
+			layout = time.RFC3339Nano
+		}
+		return v.Format(layout)
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			if f.bytes == BytesBase64 {
+				return base64.StdEncoding.EncodeToString(rv.Bytes())
+			}
+			return hex.EncodeToString(rv.Bytes())
+		}
+		if depth > maxFormatDepth {
+			return ellipsis
+		}
+		return f.formatComposite(rv, depth)
+	}
+
+	return fmt.Sprintf("%v", v)
+}
+
+// formatComposite renders the slice, array, map or struct rv as %v does,
+// with each element rendered by [cellFormatter.format]: "[a b]",
+// "map[k:v]" with keys in sorted order, or "{a b}".
+func (f cellFormatter) formatComposite(rv reflect.Value, depth int) string {
+	element := func(v reflect.Value) string {
+		if !v.CanInterface() {
+			return fmt.Sprintf("%v", v)
+		}
+		if v.Kind() == reflect.Interface && v.IsNil() {
+			return "<nil>"
+		}
+		return f.formatDepth(v.Interface(), depth+1)
+	}
+
+	var b strings.Builder
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		b.WriteByte('[')
+		for i := range rv.Len() {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(element(rv.Index(i)))
+		}
+		b.WriteByte(']')
+	case reflect.Map:
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return lessKey(keys[i], keys[j]) })
+		b.WriteString("map[")
+		for i, key := range keys {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(element(key) + ":" + element(rv.MapIndex(key)))
+		}
+		b.WriteByte(']')
+	case reflect.Struct:
+		b.WriteByte('{')
+		for i := range rv.NumField() {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(element(rv.Field(i)))
+		}
+		b.WriteByte('}')
+	}
+	return b.String()
+}
+
+// lessKey orders map keys for display: numbers numerically, strings and
+// booleans by value, and other keys by their %v rendering.
+func lessKey(a, b reflect.Value) bool {
+	if a.Kind() == b.Kind() {
+		switch numberKind(a.Kind()) {
+		case 'i':
+			return a.Int() < b.Int()
+		case 'u':
+			return a.Uint() < b.Uint()
+		case 'f':
+			return a.Float() < b.Float()
+		}
+		switch a.Kind() {
+		case reflect.String:
+			return a.String() < b.String()
+		case reflect.Bool:
+			return !a.Bool() && b.Bool()
+		}
+	}
+	return fmt.Sprintf("%v", a) < fmt.Sprintf("%v", b)
+}
+
+var errorType = reflect.TypeFor[error]()
+
+// hasPointerMethods reports whether pointer type t has an Error or String
+// method that its element type lacks, so that t must not be dereferenced.
+func hasPointerMethods(t reflect.Type) bool {
+	for _, iface := range []reflect.Type{errorType, stringerType} {
+		if t.Implements(iface) && !t.Elem().Implements(iface) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatterFor returns the registered formatter for typ: the most recent
+// one for typ itself, else the most recent one for an interface typ
+// implements. It returns nil if there is none.
+func (f cellFormatter) formatterFor(typ reflect.Type) func(v any) string {
+	for i := len(f.formatters) - 1; i >= 0; i-- {
+		if f.formatters[i].typ == typ {
+			return f.formatters[i].format
+		}
+	}
+	for i := len(f.formatters) - 1; i >= 0; i-- {
+		if f.formatters[i].typ.Kind() == reflect.Interface && typ.Implements(f.formatters[i].typ) {
+			return f.formatters[i].format
+		}
+	}
+	return nil
+}
+
+// ellipsis marks text left out of an elided cell.
+const ellipsis = "…"
+
+// elide shortens s to at most width display columns, replacing the end
+// with an ellipsis. A width of 0 or less means no limit.
+func elide(s string, width int) string {
+	if width <= 0 || displayWidth(s) <= width {
+		return s
+	}
+	return head(s, width-1) + ellipsis
+}
+
+// head returns the longest prefix of s that fits in width columns.
+func head(s string, width int) string {
+	used := 0
+	for i, r := range s {
+		used += runeWidth(r)
+		if used > width {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// tail returns the longest suffix of s that fits in width columns.
+func tail(s string, width int) string {
+	runes := []rune(s)
+	used := 0
+	for i := len(runes) - 1; i >= 0; i-- {
+		used += runeWidth(runes[i])
+		if used > width {
+			return string(runes[i+1:])
+		}
+	}
+	return s
+}
+
+// inlineDiff renders two differing strings, a expected and b actual, for
+// cells about width columns wide. Both cells show the same window of text
+// around the region where the strings differ; the expected cell shows a's
+// text there and the actual cell shows the change as [old→new], as in
+// "…New Y[o→a]rk…". Text outside the window is elided with "…".
+func inlineDiff(a, b string, width int) (string, string) {
+	runesA, runesB := []rune(a), []rune(b)
+
+	prefix := 0
+	for prefix < len(runesA) && prefix < len(runesB) && runesA[prefix] == runesB[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(runesA)-prefix && suffix < len(runesB)-prefix &&
+		runesA[len(runesA)-1-suffix] == runesB[len(runesB)-1-suffix] {
+		suffix++
+	}
+
+	// Each side of the change gets at most a third of the width, and the
+	// shared text around it splits what is left; space one side does not
+	// need goes to the other.
+	part := max(width/3, 2)
+	oldText := elide(string(runesA[prefix:len(runesA)-suffix]), part)
+	newText := elide(string(runesB[prefix:len(runesB)-suffix]), part)
+	change := "[" + oldText + "→" + newText + "]"
+
+	before := string(runesA[:prefix])
+	after := string(runesA[len(runesA)-suffix:])
+	budget := max(width-displayWidth(change), 2)
+	afterBudget := budget / 2
+	beforeBudget := budget - afterBudget
+	if w := displayWidth(after); w < afterBudget {
+		beforeBudget += afterBudget - w
+	}
+	if w := displayWidth(before); w < beforeBudget {
+		afterBudget += beforeBudget - w
+	}
+
+	if displayWidth(before) > beforeBudget {
+		before = ellipsis + tail(before, beforeBudget-1)
+	}
+	if displayWidth(after) > afterBudget {
+		after = head(after, afterBudget-1) + ellipsis
+	}
+
+	return before + oldText + after, before + change + after
+}
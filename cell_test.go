@@ -0,0 +1,200 @@
+package datadiff
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type celsius float64
+
+func (c celsius) String() string {
+	return fmt.Sprintf("%.1f°C", float64(c))
+}
+
+type labelled struct {
+	Name string
+}
+
+func (l *labelled) String() string {
+	return "label:" + l.Name
+}
+
+type rawBytes []byte
+
+func TestCellFormatter_Defaults(t *testing.T) {
+	n := 42
+	np := &n
+	when := time.Date(2024, 3, 1, 12, 30, 0, 500, time.UTC)
+	var nilLabel *labelled
+
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{name: "nil", value: nil, want: "<nil>"},
+		{name: "int", value: 7, want: "7"},
+		{name: "stringer", value: celsius(21.5), want: "21.5°C"},
+		{name: "pointer stringer", value: &labelled{Name: "a"}, want: "label:a"},
+		{name: "nil pointer stringer", value: nilLabel, want: "<nil>"},
+		{name: "error", value: errors.New("boom"), want: "boom"},
+		{name: "time", value: when, want: "2024-03-01T12:30:00.0000005Z"},
+		{name: "bytes", value: []byte{0xde, 0xad, 0xbe, 0xef}, want: "deadbeef"},
+		{name: "pointer", value: &n, want: "42"},
+		{name: "pointer to pointer", value: &np, want: "42"},
+		{name: "pointer to time", value: &when, want: "2024-03-01T12:30:00.0000005Z"},
+		{name: "value type", value: netip.MustParseAddr("10.0.0.1"), want: "10.0.0.1"},
+		{name: "missing key", value: MissingKey{}, want: "<missing>"},
+		{name: "named bytes", value: rawBytes("ab"), want: "6162"},
+		{name: "time slice", value: []time.Time{when, when}, want: "[2024-03-01T12:30:00.0000005Z 2024-03-01T12:30:00.0000005Z]"},
+		{name: "time map", value: map[string]*time.Time{"b": &when, "a": nil}, want: "map[a:<nil> b:2024-03-01T12:30:00.0000005Z]"},
+		{name: "int keys", value: map[int]string{10: "x", 9: "y"}, want: "map[9:y 10:x]"},
+		{name: "struct", value: struct {
+			Name string
+			At   time.Time
+			Raw  []byte
+		}{"a", when, []byte{1}}, want: "{a 2024-03-01T12:30:00.0000005Z 01}"},
+		{name: "nested bytes", value: [][]byte{{0xab}, nil}, want: "[ab ]"},
+		{name: "interface elements", value: []any{1, nil, "x"}, want: "[1 <nil> x]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (cellFormatter{}).format(tt.value); got != tt.want {
+				t.Fatalf("format mismatch: got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCellFormatter_MonotonicClock(t *testing.T) {
+	now := time.Now()
+	for _, value := range []any{now, []time.Time{now}, map[string]time.Time{"at": now}} {
+		if got := (cellFormatter{}).format(value); strings.Contains(got, "m=") {
+			t.Fatalf("expected time without monotonic clock reading, got %q", got)
+		}
+	}
+}
+
+func TestCellFormatter_Cycle(t *testing.T) {
+	type node struct {
+		Name string
+		Next *node
+	}
+	n := &node{Name: "a"}
+	n.Next = n
+	if got := (cellFormatter{}).format(n); !strings.HasPrefix(got, "{a {a {a") || !strings.Contains(got, ellipsis) {
+		t.Fatalf("expected a cyclic value to be cut off, got %q", got)
+	}
+}
+
+func TestCellFormatter_Options(t *testing.T) {
+	f := cellFormatter{
+		timeLayout: time.DateOnly,
+		bytes:      BytesBase64,
+		formatters: []typedFormatter{
+			{typ: reflect.TypeFor[int](), format: func(v any) string { return "first" }},
+			{typ: reflect.TypeFor[int](), format: func(v any) string { return fmt.Sprintf("#%d", v) }},
+			{typ: reflect.TypeFor[error](), format: func(v any) string { return "error" }},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{name: "time layout", value: time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC), want: "2024-03-01"},
+		{name: "base64", value: []byte("hi"), want: "aGk="},
+		{name: "named base64", value: rawBytes("hi"), want: "aGk="},
+		{name: "time layout in slice", value: []time.Time{time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)}, want: "[2024-03-01]"},
+		{name: "formatter in map", value: map[string]int{"a": 1}, want: "map[a:#1]"},
+		{name: "latest formatter wins", value: 7, want: "#7"},
+		{name: "interface formatter", value: errors.New("boom"), want: "error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := f.format(tt.value); got != tt.want {
+				t.Fatalf("format mismatch: got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestElide(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{s: "Boston", width: 10, want: "Boston"},
+		{s: "Boston", width: 6, want: "Boston"},
+		{s: "Boston", width: 5, want: "Bost…"},
+		{s: "東京タワー", width: 6, want: "東京…"},
+		{s: "Boston", width: 0, want: "Boston"},
+	}
+
+	for _, tt := range tests {
+		if got := elide(tt.s, tt.width); got != tt.want {
+			t.Fatalf("elide(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestInlineDiff(t *testing.T) {
+	tests := []struct {
+		name         string
+		a, b         string
+		width        int
+		wantExpected string
+		wantActual   string
+	}{
+		{
+			name:         "replacement",
+			a:            "The quick brown fox jumps over the lazy dog near New York City at dawn",
+			b:            "The quick brown fox jumps over the lazy dog near New Yark City at dawn",
+			width:        40,
+			wantExpected: "…lazy dog near New York City at dawn",
+			wantActual:   "…lazy dog near New Y[o→a]rk City at dawn",
+		},
+		{
+			name:         "insertion",
+			a:            "SELECT id, name FROM users WHERE active = 1 ORDER BY name",
+			b:            "SELECT id, name, email FROM users WHERE active = 1 ORDER BY name",
+			width:        40,
+			wantExpected: "SELECT id, name FROM users WH…",
+			wantActual:   "SELECT id, name[→, email] FROM users WH…",
+		},
+		{
+			name:         "deletion at end",
+			a:            "a long description that ends with a typo!!",
+			b:            "a long description that ends with a typo",
+			width:        20,
+			wantExpected: "…ds with a typo!!",
+			wantActual:   "…ds with a typo[!!→]",
+		},
+		{
+			name:         "long change",
+			a:            `{"id":1,"tags":["a","b","c","d","e","f"]}`,
+			b:            `{"id":2,"tags":[]}`,
+			width:        24,
+			wantExpected: `…":1,"tags…]}`,
+			wantActual:   `…":[1,"tags…→2,"tags…]]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected, actual := inlineDiff(tt.a, tt.b, tt.width)
+			if expected != tt.wantExpected || actual != tt.wantActual {
+				t.Fatalf("inlineDiff mismatch:\ngot  %q, %q\nwant %q, %q", expected, actual, tt.wantExpected, tt.wantActual)
+			}
+		})
+	}
+}
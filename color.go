@@ -0,0 +1,101 @@
+package datadiff
+
+import (
+	"fmt"
+	"os"
+)
+
+// ColorMode selects whether the diff output uses ANSI colors.
+type ColorMode int
+
+const (
+	// ColorAuto uses colors only when standard output is a terminal and
+	// the NO_COLOR environment variable is unset or empty.
+	ColorAuto ColorMode = iota
+
+	// ColorAlways always uses colors.
+	ColorAlways
+
+	// ColorNever never uses colors.
+	ColorNever
+)
+
+// WithColor sets whether the diff output uses ANSI colors. The default is
+// [ColorAuto]. Without colors, mismatched cells are marked *like this*.
+func WithColor(mode ColorMode) Option {
+	return func(o *options) error {
+		if mode != ColorAuto && mode != ColorAlways && mode != ColorNever {
+			return fmt.Errorf("datadiff: WithColor: unknown color mode %d", mode)
+		}
+		o.color = mode
+		return nil
+	}
+}
+
+const (
+	ansiReset  = "\033[0m"
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+)
+
+// outputStyle controls how values and highlights are rendered.
+type outputStyle struct {
+	cells cellFormatter
+
+	// plain disables ANSI colors; mismatched cells are then marked with
+	// asterisks instead.
+	plain bool
+
+	maxWidth int // widest cell before elision; 0 means no limit
+
+	// collapse hides matching rows further than context rows from a
+	// difference.
+	collapse bool
+	context  int
+}
+
+// newOutputStyle returns the output style configured by opts, resolving
+// ColorAuto against the environment.
+func newOutputStyle(opts options) outputStyle {
+	return outputStyle{
+		cells: newCellFormatter(opts),
+		plain: !colorEnabled(opts.color),
+
+		maxWidth: opts.cellWidth(),
+
+		collapse: !opts.showAllRows,
+		context:  opts.contextRows(),
+	}
+}
+
+// colorize wraps value in color, or returns it unchanged if s is plain.
+func (s outputStyle) colorize(value, color string) string {
+	if s.plain {
+		return value
+	}
+	return color + value + ansiReset
+}
+
+// mismatched highlights a mismatched cell value.
+func (s outputStyle) mismatched(value string) string {
+	if s.plain {
+		return "*" + value + "*"
+	}
+	return ansiRed + value + ansiReset
+}
+
+// colorEnabled resolves mode for output to standard output.
+func colorEnabled(mode ColorMode) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return isTerminal(os.Stdout)
+}
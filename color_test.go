@@ -0,0 +1,97 @@
+package datadiff
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestColorEnabled(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+
+	if !colorEnabled(ColorAlways) {
+		t.Fatal("expected ColorAlways to enable colors")
+	}
+	if colorEnabled(ColorNever) {
+		t.Fatal("expected ColorNever to disable colors")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if colorEnabled(ColorAuto) {
+		t.Fatal("expected NO_COLOR to disable colors in ColorAuto")
+	}
+	if !colorEnabled(ColorAlways) {
+		t.Fatal("expected ColorAlways to override NO_COLOR")
+	}
+}
+
+func TestOutputStyle(t *testing.T) {
+	colored := outputStyle{}
+	if got := colored.mismatched("31"); got != ansiRed+"31"+ansiReset {
+		t.Fatalf("colored mismatch: got %q", got)
+	}
+
+	plain := outputStyle{plain: true}
+	if got := plain.mismatched("31"); got != "*31*" {
+		t.Fatalf("plain mismatch: got %q, want %q", got, "*31*")
+	}
+	if got := plain.colorize("✓", ansiGreen); got != "✓" {
+		t.Fatalf("plain colorize: got %q, want %q", got, "✓")
+	}
+}
+
+func TestWithColor(t *testing.T) {
+	a := []Person{{Name: "Alice", Age: 30}}
+	b := []Person{{Name: "Alice", Age: 31}}
+
+	result, err := Diff(a, b, WithColor(ColorNever))
+	if err != nil {
+		t.Fatalf("Diff returned unexpected error: %v", err)
+	}
+	out := result.String()
+	if strings.Contains(out, "\033[") {
+		t.Fatalf("expected no ANSI escapes, got %q", out)
+	}
+	if !strings.Contains(out, "*31* (+1)") {
+		t.Fatalf("expected plain mismatch marker, got %q", out)
+	}
+
+	result, err = Diff(a, b, WithColor(ColorAlways))
+	if err != nil {
+		t.Fatalf("Diff returned unexpected error: %v", err)
+	}
+	if !strings.Contains(result.String(), ansiRed+"31"+ansiReset) {
+		t.Fatalf("expected ANSI highlight, got %q", result.String())
+	}
+
+	if _, err := Diff(a, b, WithColor(ColorMode(7))); err == nil || !strings.Contains(err.Error(), "unknown color mode 7") {
+		t.Fatalf("expected unknown color mode error, got %v", err)
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux", "darwin", "dragonfly", "freebsd", "netbsd", "openbsd", "windows":
+	default:
+		t.Skipf("no terminal check on %s", runtime.GOOS)
+	}
+
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Skipf("cannot open %s: %v", os.DevNull, err)
+	}
+	defer devNull.Close()
+	if isTerminal(devNull) {
+		t.Fatalf("expected %s not to be a terminal", os.DevNull)
+	}
+
+	file, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if isTerminal(file) {
+		t.Fatal("expected a regular file not to be a terminal")
+	}
+}
@@ -1,14 +1,21 @@
 package datadiff
 
-import "reflect"
+import (
+	"fmt"
+	"strings"
+)
 
-// compare produces a diffResult from two datasets and the parsed flags.
+// compare produces a diffResult from two datasets and the parsed options.
 //
 // Modes:
 //   - Default (strict): rows compared index-by-index; lengths must match.
 //   - ignoreOrder=true: rows matched by best-fit; order does not matter.
+//   - optimalMatching=true: with ignoreOrder, best-fit minimises the total
+//     number of mismatched fields instead of matching greedily.
+//   - keyColumns or keyFunc set: rows joined on key values; order does
+//     not matter.
 //   - ignoreLengths=true: extra rows reported but do not set equal=false.
-func compare(a, b dataset, ignoreOrder, ignoreLengths bool) diffResult {
+func compare(a, b dataset, opts options) diffResult {
 	result := diffResult{
 		equal:    true,
 		typeName: a.typeName,
@@ -22,166 +29,234 @@ func compare(a, b dataset, ignoreOrder, ignoreLengths bool) diffResult {
 		result.columns = b.columns
 	}
 
-	if ignoreOrder {
-		compareUnordered(&result, a, b, ignoreLengths)
+	m := newMatcher(result.columns, opts)
+	m.byValue = a.elemType != b.elemType
+
+	if opts.keyFunc != nil {
+		compareKeyed(&result, a, b, m, funcKey(opts.keyFunc), opts.ignoreLengths)
+		return result
+	}
+
+	if len(opts.keyColumns) > 0 {
+		compareKeyed(&result, a, b, m, columnKey(result.columns, opts.keyColumns), opts.ignoreLengths)
 		return result
 	}
 
-	compareOrdered(&result, a, b, ignoreLengths)
+	if opts.ignoreOrder {
+		compareUnordered(&result, a, b, m, opts)
+		return result
+	}
+
+	compareOrdered(&result, a, b, m, opts.ignoreLengths)
 	return result
 }
 
-func compareOrdered(result *diffResult, a, b dataset, ignoreLengths bool) {
+func compareOrdered(result *diffResult, a, b dataset, m *matcher, ignoreLengths bool) {
 	limit := len(a.rows)
 	if len(b.rows) < limit {
 		limit = len(b.rows)
 	}
 
 	for i := 0; i < limit; i++ {
-		mismatch, mismatchCount := fieldMismatch(a.rows[i].values, b.rows[i].values, len(result.columns))
-		if mismatchCount == 0 {
-			result.diffs = append(result.diffs, rowDiff{
-				index:   i,
-				status:  rowMatch,
-				valuesA: a.rows[i].values,
-				valuesB: b.rows[i].values,
-			})
-			continue
-		}
-
-		result.equal = false
-		result.diffs = append(result.diffs, rowDiff{
-			index:    i,
-			status:   rowMismatch,
-			valuesA:  a.rows[i].values,
-			valuesB:  b.rows[i].values,
-			mismatch: mismatch,
-		})
+		result.add(pairDiff(m, i, a.rows[i], b.rows[i]), ignoreLengths)
 	}
 
 	for i := limit; i < len(a.rows); i++ {
-		result.diffs = append(result.diffs, rowDiff{
-			index:   i,
-			status:  rowExtra,
-			valuesA: a.rows[i].values,
-		})
-		if !ignoreLengths {
-			result.equal = false
-		}
+		result.add(sideDiff(i, rowExtra, a.rows[i], true), ignoreLengths)
 	}
 
 	for i := limit; i < len(b.rows); i++ {
-		result.diffs = append(result.diffs, rowDiff{
-			index:   i,
-			status:  rowExtra,
-			valuesB: b.rows[i].values,
-		})
-		if !ignoreLengths {
-			result.equal = false
+		result.add(sideDiff(i, rowExtra, b.rows[i], false), ignoreLengths)
+	}
+}
+
+// compareUnordered pairs rows regardless of position. Identical rows are
+// paired first through a hash lookup; only the leftovers go through greedy
+// best-fit matching or, with optimalMatching, a minimal-cost assignment.
+// Rows are reported in listA order, followed by unpaired rows from listB.
+func compareUnordered(result *diffResult, a, b dataset, m *matcher, opts options) {
+	pairs := exactPairs(a, b, m)
+	pairFuzzy(pairs, a, b, m, opts.optimalMatching)
+
+	paired := make([]bool, len(b.rows))
+	for i, rowA := range a.rows {
+		j := pairs[i]
+		if j < 0 {
+			result.add(sideDiff(i, rowExtra, rowA, true), opts.ignoreLengths)
+			continue
+		}
+
+		paired[j] = true
+		result.add(pairDiff(m, i, rowA, b.rows[j]), opts.ignoreLengths)
+	}
+
+	for j := range b.rows {
+		if !paired[j] {
+			result.add(sideDiff(j, rowExtra, b.rows[j], false), opts.ignoreLengths)
 		}
 	}
 }
 
-func compareUnordered(result *diffResult, a, b dataset, ignoreLengths bool) {
-	type unmatchedRow struct {
-		index  int
-		values []any
+// compareKeyed joins rows on the key returned by keyOf, which gives a join
+// key and a display label for a row. Rows are reported in listA order,
+// followed by rows whose key appears only in listB. Keys that occur more
+// than once in either list are reported as duplicates and their rows are
+// not compared.
+func compareKeyed(result *diffResult, a, b dataset, m *matcher, keyOf func(row) (string, string), ignoreLengths bool) {
+	type keyGroup struct {
+		label string
+		rowsA []int
+		rowsB []int
 	}
 
-	unmatched := make([]unmatchedRow, len(b.rows))
+	groups := make(map[string]*keyGroup)
+	var order []string
+	addRow := func(r row, index int, fromA bool) {
+		key, label := keyOf(r)
+		group, ok := groups[key]
+		if !ok {
+			group = &keyGroup{label: label}
+			groups[key] = group
+			order = append(order, key)
+		}
+		if fromA {
+			group.rowsA = append(group.rowsA, index)
+		} else {
+			group.rowsB = append(group.rowsB, index)
+		}
+	}
+
+	for i := range a.rows {
+		addRow(a.rows[i], i, true)
+	}
 	for i := range b.rows {
-		unmatched[i] = unmatchedRow{index: i, values: b.rows[i].values}
+		addRow(b.rows[i], i, false)
 	}
 
-	for i, rowA := range a.rows {
-		if len(unmatched) == 0 {
-			result.diffs = append(result.diffs, rowDiff{index: i, status: rowExtra, valuesA: rowA.values})
-			if !ignoreLengths {
-				result.equal = false
-			}
-			continue
-		}
+	for _, key := range order {
+		group := groups[key]
 
-		bestCandidate := -1
-		bestMismatchCount := len(result.columns) + 1
-		var bestMismatch []bool
-		for j, candidate := range unmatched {
-			mismatch, mismatchCount := fieldMismatch(rowA.values, candidate.values, len(result.columns))
-			if mismatchCount == 0 {
-				bestCandidate = j
-				bestMismatch = mismatch
-				bestMismatchCount = 0
-				break
+		switch {
+		case len(group.rowsA) > 1 || len(group.rowsB) > 1:
+			for _, i := range group.rowsA {
+				result.add(sideDiff(i, rowDuplicateKey, a.rows[i], true).withKey(group.label), ignoreLengths)
 			}
-
-			if mismatchCount < bestMismatchCount {
-				bestCandidate = j
-				bestMismatch = mismatch
-				bestMismatchCount = mismatchCount
+			for _, i := range group.rowsB {
+				result.add(sideDiff(i, rowDuplicateKey, b.rows[i], false).withKey(group.label), ignoreLengths)
 			}
+		case len(group.rowsB) == 0:
+			i := group.rowsA[0]
+			result.add(sideDiff(i, rowMissingKey, a.rows[i], true).withKey(group.label), ignoreLengths)
+		case len(group.rowsA) == 0:
+			i := group.rowsB[0]
+			result.add(sideDiff(i, rowMissingKey, b.rows[i], false).withKey(group.label), ignoreLengths)
+		default:
+			i, j := group.rowsA[0], group.rowsB[0]
+			result.add(pairDiff(m, i, a.rows[i], b.rows[j]).withKey(group.label), ignoreLengths)
 		}
+	}
+}
 
-		if bestCandidate < 0 {
-			result.diffs = append(result.diffs, rowDiff{index: i, status: rowExtra, valuesA: rowA.values})
-			if !ignoreLengths {
-				result.equal = false
-			}
-			continue
-		}
+// pairDiff compares two paired rows.
+func pairDiff(m *matcher, index int, a, b row) rowDiff {
+	diff := rowDiff{
+		index:   index,
+		status:  rowMatch,
+		valuesA: a.values,
+		valuesB: b.values,
+		nilA:    a.isNil,
+		nilB:    b.isNil,
+	}
 
-		candidate := unmatched[bestCandidate]
-		unmatched = append(unmatched[:bestCandidate], unmatched[bestCandidate+1:]...)
+	if mismatch, mismatchCount := m.rowMismatch(a, b); mismatchCount > 0 {
+		diff.status = rowMismatch
+		diff.mismatch = mismatch
+	}
 
-		if bestMismatchCount == 0 {
-			result.diffs = append(result.diffs, rowDiff{
-				index:   i,
-				status:  rowMatch,
-				valuesA: rowA.values,
-				valuesB: candidate.values,
-			})
-			continue
-		}
+	return diff
+}
+
+// sideDiff describes a row present in only one list: listA if fromA,
+// listB otherwise.
+func sideDiff(index int, status rowStatus, r row, fromA bool) rowDiff {
+	if fromA {
+		return rowDiff{index: index, status: status, valuesA: r.values, nilA: r.isNil}
+	}
+	return rowDiff{index: index, status: status, valuesB: r.values, nilB: r.isNil}
+}
 
-		result.equal = false
-		result.diffs = append(result.diffs, rowDiff{
-			index:    i,
-			status:   rowMismatch,
-			valuesA:  rowA.values,
-			valuesB:  candidate.values,
-			mismatch: bestMismatch,
-		})
-	}
-
-	for _, candidate := range unmatched {
-		result.diffs = append(result.diffs, rowDiff{
-			index:   candidate.index,
-			status:  rowExtra,
-			valuesB: candidate.values,
-		})
+func (d rowDiff) withKey(key string) rowDiff {
+	d.key = key
+	return d
+}
+
+// add appends diff and clears result.equal unless the diff is a match,
+// or a row present in only one list while ignoreLengths is set.
+func (r *diffResult) add(diff rowDiff, ignoreLengths bool) {
+	r.diffs = append(r.diffs, diff)
+
+	switch diff.status {
+	case rowMatch:
+	case rowExtra, rowMissingKey:
 		if !ignoreLengths {
-			result.equal = false
+			r.equal = false
 		}
+	default:
+		r.equal = false
 	}
 }
 
-func fieldMismatch(valuesA, valuesB []any, columnCount int) ([]bool, int) {
-	mismatch := make([]bool, columnCount)
-	mismatchCount := 0
+// columnKey returns a keyOf function for [compareKeyed] that joins rows on
+// keyColumns, a subset of columns.
+func columnKey(columns, keyColumns []string) func(row) (string, string) {
+	keyIndexes := make([]int, len(keyColumns))
+	for i, name := range keyColumns {
+		keyIndexes[i] = columnIndex(columns, name)
+	}
+
+	return func(r row) (string, string) {
+		return rowKey(r, keyIndexes, keyColumns)
+	}
+}
 
-	for i := 0; i < columnCount; i++ {
-		if i >= len(valuesA) || i >= len(valuesB) {
-			mismatch[i] = true
-			mismatchCount++
-			continue
+// funcKey returns a keyOf function for [compareKeyed] that joins rows on
+// the key keyFunc computes from their elements. Rows are labelled with
+// the key value, such as "key=alice@example.com".
+func funcKey(keyFunc func(elem any) any) func(row) (string, string) {
+	return func(r row) (string, string) {
+		if r.isNil {
+			return nilRowKey, nilRowLabel
 		}
+		key := keyFunc(r.elem)
+		return fmt.Sprintf("%#v", key), fmt.Sprintf("key=%v", key)
+	}
+}
 
-		if reflect.DeepEqual(valuesA[i], valuesB[i]) {
-			continue
+// nilRowKey is the join key shared by all nil rows.
+const nilRowKey = "\x00nil"
+
+// rowKey returns a join key for the key columns of r and a
+// human-readable label such as "ID=7". Nil rows share one key.
+func rowKey(r row, keyIndexes []int, keyColumns []string) (string, string) {
+	if r.isNil {
+		return nilRowKey, nilRowLabel
+	}
+
+	values := r.values
+	var key, label strings.Builder
+	for i, index := range keyIndexes {
+		var value any
+		if index < len(values) {
+			value = values[index]
 		}
 
-		mismatch[i] = true
-		mismatchCount++
+		if i > 0 {
+			key.WriteByte(0)
+			label.WriteString(", ")
+		}
+		fmt.Fprintf(&key, "%#v", value)
+		fmt.Fprintf(&label, "%s=%v", keyColumns[i], value)
 	}
 
-	return mismatch, mismatchCount
+	return key.String(), label.String()
 }
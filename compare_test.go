@@ -33,7 +33,7 @@ func TestCompare_StrictEqual(t *testing.T) {
 		[]any{"Bob", 25},
 	)
 
-	got := compare(a, b, false, false)
+	got := compare(a, b, options{})
 	if !got.equal {
 		t.Fatal("expected equal=true")
 	}
@@ -61,7 +61,7 @@ func TestCompare_StrictMismatch(t *testing.T) {
 		[]any{"Bob", 25},
 	)
 
-	got := compare(a, b, false, false)
+	got := compare(a, b, options{})
 	if got.equal {
 		t.Fatal("expected equal=false")
 	}
@@ -89,7 +89,7 @@ func TestCompare_StrictDifferentLengths(t *testing.T) {
 		[]any{"Bob", 25},
 	)
 
-	got := compare(a, b, false, false)
+	got := compare(a, b, options{})
 	if got.equal {
 		t.Fatal("expected equal=false")
 	}
@@ -117,7 +117,7 @@ func TestCompare_StrictDifferentLengths_IgnoreLengths(t *testing.T) {
 		[]any{"Bob", 25},
 	)
 
-	got := compare(a, b, false, true)
+	got := compare(a, b, options{ignoreLengths: true})
 	if !got.equal {
 		t.Fatal("expected equal=true when ignoreLengths=true and overlap matches")
 	}
@@ -133,7 +133,7 @@ func TestCompare_StrictBothEmpty(t *testing.T) {
 	a := makePersonDataset()
 	b := makePersonDataset()
 
-	got := compare(a, b, false, false)
+	got := compare(a, b, options{})
 	if !got.equal {
 		t.Fatal("expected equal=true")
 	}
@@ -146,7 +146,7 @@ func TestCompare_StrictOneEmpty(t *testing.T) {
 	a := makePersonDataset()
 	b := makePersonDataset([]any{"Alice", 30})
 
-	got := compare(a, b, false, false)
+	got := compare(a, b, options{})
 	if got.equal {
 		t.Fatal("expected equal=false")
 	}
@@ -173,7 +173,7 @@ func TestCompare_UnorderedExactMatch(t *testing.T) {
 		[]any{"Bob", 25},
 	)
 
-	got := compare(a, b, true, false)
+	got := compare(a, b, options{ignoreOrder: true})
 	if !got.equal {
 		t.Fatal("expected equal=true")
 	}
@@ -197,7 +197,7 @@ func TestCompare_UnorderedMismatch(t *testing.T) {
 		[]any{"Charlie", 25},
 	)
 
-	got := compare(a, b, true, false)
+	got := compare(a, b, options{ignoreOrder: true})
 	if got.equal {
 		t.Fatal("expected equal=false")
 	}
@@ -221,7 +221,7 @@ func TestCompare_UnorderedDuplicates(t *testing.T) {
 		[]any{"X", 1},
 	)
 
-	got := compare(a, b, true, false)
+	got := compare(a, b, options{ignoreOrder: true})
 	if !got.equal {
 		t.Fatal("expected equal=true for duplicated unordered rows")
 	}
@@ -245,7 +245,7 @@ func TestCompare_UnorderedPartialMatch(t *testing.T) {
 		[]any{"Chuck", 35},
 	)
 
-	got := compare(a, b, true, false)
+	got := compare(a, b, options{ignoreOrder: true})
 	if got.equal {
 		t.Fatal("expected equal=false")
 	}
@@ -277,7 +277,7 @@ func TestCompare_UnorderedDifferentLengths(t *testing.T) {
 		[]any{"Charlie", 35},
 	)
 
-	got := compare(a, b, true, true)
+	got := compare(a, b, options{ignoreOrder: true, ignoreLengths: true})
 	if !got.equal {
 		t.Fatal("expected equal=true with ignoreOrder+ignoreLengths when common rows match")
 	}
@@ -297,7 +297,7 @@ func TestCompare_MismatchFieldTracking(t *testing.T) {
 	a := makeDataset("Person", []string{"Name", "Age", "City"}, []any{"Alice", 30, "NY"})
 	b := makeDataset("Person", []string{"Name", "Age", "City"}, []any{"Alicia", 30, "SF"})
 
-	got := compare(a, b, false, false)
+	got := compare(a, b, options{})
 	if got.equal {
 		t.Fatal("expected equal=false")
 	}
@@ -310,3 +310,143 @@ func TestCompare_MismatchFieldTracking(t *testing.T) {
 		t.Fatalf("mismatch tracking mismatch: got %#v, want %#v", got.diffs[0].mismatch, wantMismatch)
 	}
 }
+
+func makeUserDataset(rows ...[]any) dataset {
+	return makeDataset("User", []string{"ID", "City", "Age"}, rows...)
+}
+
+func TestCompare_KeyedMatchesByKey(t *testing.T) {
+	a := makeUserDataset(
+		[]any{1, "Boston", 30},
+		[]any{7, "Boston", 30},
+	)
+	b := makeUserDataset(
+		[]any{7, "Boston", 31},
+		[]any{1, "Boston", 30},
+	)
+
+	got := compare(a, b, options{keyColumns: []string{"ID"}})
+	if got.equal {
+		t.Fatal("expected equal=false")
+	}
+	if len(got.diffs) != 2 {
+		t.Fatalf("diff count mismatch: got %d, want %d", len(got.diffs), 2)
+	}
+
+	if got.diffs[0].status != rowMatch || got.diffs[0].key != "ID=1" {
+		t.Fatalf("expected ID=1 to match, got status=%v key=%q", got.diffs[0].status, got.diffs[0].key)
+	}
+
+	mismatch := got.diffs[1]
+	if mismatch.status != rowMismatch || mismatch.key != "ID=7" {
+		t.Fatalf("expected ID=7 to mismatch, got status=%v key=%q", mismatch.status, mismatch.key)
+	}
+	wantMismatch := []bool{false, false, true}
+	if !reflect.DeepEqual(mismatch.mismatch, wantMismatch) {
+		t.Fatalf("mismatch flags mismatch: got %#v, want %#v", mismatch.mismatch, wantMismatch)
+	}
+}
+
+func TestCompare_KeyedMissingKeys(t *testing.T) {
+	a := makeUserDataset(
+		[]any{1, "Boston", 30},
+		[]any{2, "Denver", 40},
+	)
+	b := makeUserDataset(
+		[]any{1, "Boston", 30},
+		[]any{3, "Austin", 50},
+	)
+
+	got := compare(a, b, options{keyColumns: []string{"ID"}})
+	if got.equal {
+		t.Fatal("expected equal=false")
+	}
+	if len(got.diffs) != 3 {
+		t.Fatalf("diff count mismatch: got %d, want %d", len(got.diffs), 3)
+	}
+
+	onlyA := got.diffs[1]
+	if onlyA.status != rowMissingKey || onlyA.key != "ID=2" || onlyA.valuesA == nil || onlyA.valuesB != nil {
+		t.Fatalf("expected ID=2 only in expected, got %#v", onlyA)
+	}
+
+	onlyB := got.diffs[2]
+	if onlyB.status != rowMissingKey || onlyB.key != "ID=3" || onlyB.valuesA != nil || onlyB.valuesB == nil {
+		t.Fatalf("expected ID=3 only in actual, got %#v", onlyB)
+	}
+
+	got = compare(a, b, options{keyColumns: []string{"ID"}, ignoreLengths: true})
+	if !got.equal {
+		t.Fatal("expected equal=true for missing keys with ignoreLengths")
+	}
+}
+
+func TestCompare_KeyedDuplicateKeys(t *testing.T) {
+	a := makeUserDataset(
+		[]any{1, "Boston", 30},
+		[]any{1, "Denver", 40},
+	)
+	b := makeUserDataset(
+		[]any{1, "Boston", 30},
+	)
+
+	got := compare(a, b, options{keyColumns: []string{"ID"}, ignoreLengths: true})
+	if got.equal {
+		t.Fatal("expected equal=false for duplicate keys")
+	}
+	if len(got.diffs) != 3 {
+		t.Fatalf("diff count mismatch: got %d, want %d", len(got.diffs), 3)
+	}
+	for i, diff := range got.diffs {
+		if diff.status != rowDuplicateKey {
+			t.Fatalf("row %d status mismatch: got %v, want %v", i, diff.status, rowDuplicateKey)
+		}
+	}
+}
+
+func TestCompare_KeyedCompositeKey(t *testing.T) {
+	a := makeUserDataset([]any{1, "Boston", 30})
+	b := makeUserDataset([]any{1, "Boston", 31})
+
+	got := compare(a, b, options{keyColumns: []string{"ID", "City"}})
+	if len(got.diffs) != 1 || got.diffs[0].status != rowMismatch {
+		t.Fatalf("expected one mismatch row, got %#v", got.diffs)
+	}
+	if got.diffs[0].key != "ID=1, City=Boston" {
+		t.Fatalf("key label mismatch: got %q", got.diffs[0].key)
+	}
+}
+
+func TestCompare_NilRows(t *testing.T) {
+	nilRow := row{values: []any{nil, nil}, isNil: true}
+	a := makePersonDataset([]any{"Alice", 30}, nil, nil)
+	a.rows[1], a.rows[2] = nilRow, nilRow
+	b := makePersonDataset(nil, []any{"Bob", 25}, nil)
+	b.rows[0], b.rows[2] = nilRow, nilRow
+
+	got := compare(a, b, options{})
+	if got.equal {
+		t.Fatal("expected equal=false")
+	}
+
+	wantStatus := []rowStatus{rowMismatch, rowMismatch, rowMatch}
+	for i, diff := range got.diffs {
+		if diff.status != wantStatus[i] {
+			t.Fatalf("row %d status mismatch: got %v, want %v", i, diff.status, wantStatus[i])
+		}
+	}
+	if !reflect.DeepEqual(got.diffs[0].mismatch, []bool{true, true}) {
+		t.Fatalf("expected nil vs non-nil to mismatch every column, got %#v", got.diffs[0].mismatch)
+	}
+	if got.diffs[0].nilA || !got.diffs[0].nilB {
+		t.Fatalf("nil flags mismatch: got nilA=%v nilB=%v", got.diffs[0].nilA, got.diffs[0].nilB)
+	}
+
+	got = compare(a, b, options{ignoreOrder: true})
+	if got.equal {
+		t.Fatal("expected unordered equal=false")
+	}
+	if got.diffs[1].status != rowMatch || !got.diffs[1].nilA || !got.diffs[1].nilB {
+		t.Fatalf("expected nil rows to pair with each other, got %+v", got.diffs[1])
+	}
+}
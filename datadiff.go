@@ -4,80 +4,180 @@
 // Inspired by github.com/MrPowers/chispa for Python DataFrames.
 package datadiff
 
-import "testing"
+import "fmt"
 
 // Version is the current module version.
 const Version = "0.1.0-dev"
 
-// Flag controls comparison behavior in [Assert].
+// Flag controls comparison behavior in [Assert]. Each flag has an
+// equivalent [Option] constructor, such as [WithIgnoreOrder].
 type Flag int
 
 const (
 	// IgnoreOrder compares lists without regard to element position.
-	// Rows are matched by finding the closest counterpart in the other list.
+	// Identical rows are paired first; the remaining rows are matched by
+	// finding the closest counterpart in the other list.
 	IgnoreOrder Flag = iota + 1
 
 	// IgnoreLengths allows lists of different lengths.
 	// Extra rows are reported in the diff output but do not cause the
 	// assertion to fail. Without this flag, differing lengths are a failure.
 	IgnoreLengths
+
+	// OptimalMatching changes how [IgnoreOrder] pairs rows: instead of
+	// matching each expected row greedily in turn, rows are paired so that
+	// the total number of mismatched fields is minimal. The reported
+	// mismatches then do not depend on the order of the input rows.
+	// Matching is O(n³), so prefer [KeyColumns] for large lists.
+	OptimalMatching
+
+	// EquateNaN treats NaN float values as equal to each other.
+	EquateNaN
+
+	// CrossType compares lists of different element types, such as a
+	// domain struct against a DTO or a []map[string]any decoded from
+	// JSON. Columns are aligned by name; columns present on only one side
+	// are reported as schema differences and fail the assertion unless
+	// excluded with [IgnoreColumns] or [OnlyColumns].
+	CrossType
+
+	// ShowAllRows lists every row in the diff table. By default, matching
+	// rows further than [WithContext] rows from a difference are collapsed
+	// into a single "… 4,812 matching rows …" line.
+	ShowAllRows
 )
 
+// Reporter is the subset of [testing.TB] used by [Assert]. It is satisfied
+// by *testing.T, *testing.B and *testing.F, and can be implemented by
+// adapters for third-party test runners.
+//
+// Fatalf is expected to stop the calling test. Assert returns immediately
+// after calling Fatalf, so implementations that do not stop execution
+// still observe a false result.
+type Reporter interface {
+	Helper()
+	Errorf(format string, args ...any)
+	Fatalf(format string, args ...any)
+}
+
 // Assert compares listA and listB and reports differences through t.
-// Both arguments must be slices of the same struct type.
+// Both arguments must hold the same struct type, or pointer to struct,
+// as a slice, an array, an iter.Seq, or a channel, which is drained until
+// closed.
 //
 // By default, comparison is strict: rows must appear in the same order
 // and both lists must have equal length. Pass [IgnoreOrder] and/or
-// [IgnoreLengths] to relax those constraints.
+// [IgnoreLengths] to relax those constraints, or [KeyColumns] to match
+// rows by key. Flags and [Option] values may be mixed in any order.
 //
 // Assert calls t.Fatalf for programmer errors (invalid flags or invalid
 // inputs) and t.Errorf for data mismatches.
 //
 // Returns true if the lists are equal under the given flags, false otherwise.
-func Assert(t *testing.T, listA, listB any, flags ...any) bool {
+func Assert(t Reporter, listA, listB any, flags ...any) bool {
 	t.Helper()
 
-	var ignoreOrder, ignoreLengths bool
-	for _, f := range flags {
-		flag, ok := f.(Flag)
-		if !ok {
-			t.Fatalf("datadiff: unknown flag type %T (expected datadiff.Flag)", f)
-			return false
-		}
+	result, err := diff(listA, listB, flags)
+	if err != nil {
+		t.Fatalf("%v", err)
+		return false
+	}
 
-		switch flag {
-		case IgnoreOrder:
-			ignoreOrder = true
-		case IgnoreLengths:
-			ignoreLengths = true
-		default:
-			t.Fatalf("datadiff: unknown flag value: %d", flag)
-			return false
-		}
+	if !result.equal {
+		t.Errorf("\n%s", formatDiff(result))
+		return false
 	}
 
-	dsA, err := extract(listA)
+	return true
+}
+
+// Diff compares listA and listB using the same engine and flags as [Assert]
+// and returns the structured outcome instead of reporting it.
+//
+// Diff returns an error for programmer errors (invalid flags or invalid
+// inputs). Data mismatches, including differing element types, are not
+// errors; they are described by the returned [Result].
+func Diff(listA, listB any, flags ...any) (Result, error) {
+	result, err := diff(listA, listB, flags)
 	if err != nil {
-		t.Fatalf("datadiff: first argument: %v", err)
-		return false
+		return Result{}, err
 	}
 
-	dsB, err := extract(listB)
+	return Result{result: result}, nil
+}
+
+// diff parses flags, extracts both inputs and compares them.
+func diff(listA, listB any, flags []any) (diffResult, error) {
+	opts, err := parseOptions(flags)
 	if err != nil {
-		t.Fatalf("datadiff: second argument: %v", err)
-		return false
+		return diffResult{}, err
 	}
 
-	if dsA.typeName != dsB.typeName {
-		t.Errorf("datadiff: type mismatch: []%s vs []%s", dsA.typeName, dsB.typeName)
-		return false
+	dsA, err := extract(listA, opts)
+	if err != nil {
+		return diffResult{}, fmt.Errorf("datadiff: first argument: %w", err)
 	}
 
-	result := compare(dsA, dsB, ignoreOrder, ignoreLengths)
-	if !result.equal {
-		t.Errorf("\n%s", formatDiff(result))
-		return false
+	dsB, err := extract(listB, opts)
+	if err != nil {
+		return diffResult{}, fmt.Errorf("datadiff: second argument: %w", err)
 	}
 
-	return true
+	if dsA.typeName != dsB.typeName && !opts.crossType {
+		_, _, schema := alignSchemas(dsA, dsB)
+		return diffResult{
+			typeName:      dsA.typeName,
+			typeMismatch:  true,
+			otherTypeName: dsB.typeName,
+			schema:        schema,
+			style:         newOutputStyle(opts),
+		}, nil
+	}
+
+	if dsA.maps && dsB.maps {
+		columns := unionColumns(dsA.columns, dsB.columns)
+		dsA, dsB = dsA.align(columns), dsB.align(columns)
+	}
+
+	known := dsA.columns
+	for _, column := range dsB.columns {
+		if columnIndex(known, column) < 0 {
+			known = append(known[:len(known):len(known)], column)
+		}
+	}
+
+	if len(dsA.keyColumns) == 0 {
+		dsA.keyColumns = dsB.keyColumns
+	}
+	if err := resolveKeyColumns(&opts, dsA); err != nil {
+		return diffResult{}, err
+	}
+	if err := resolveKeyColumns(&opts, dsB); err != nil {
+		return diffResult{}, err
+	}
+
+	if dsA, err = selectColumns(dsA, known, opts); err != nil {
+		return diffResult{}, err
+	}
+	if dsB, err = selectColumns(dsB, known, opts); err != nil {
+		return diffResult{}, err
+	}
+
+	dsA, dsB, schema := alignSchemas(dsA, dsB)
+
+	if err := validateColumnOptions(dsA, opts); err != nil {
+		return diffResult{}, err
+	}
+
+	result := compare(dsA, dsB, opts)
+	result.style = newOutputStyle(opts)
+	if dsB.typeName != dsA.typeName {
+		result.otherTypeName = dsB.typeName
+	}
+	if !schema.empty() {
+		result.schema = schema
+		result.equal = false
+	}
+
+	return result, nil
 }
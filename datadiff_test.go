@@ -1,10 +1,17 @@
 package datadiff
 
 import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
 	"os"
 	"os/exec"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 )
 
 type Person struct {
@@ -101,7 +108,7 @@ func TestAssert_TypeMismatch(t *testing.T) {
 }
 
 func TestAssert_InvalidFlag(t *testing.T) {
-	assertScenarioFails(t, "invalid-flag", "datadiff: unknown flag type string (expected datadiff.Flag)")
+	assertScenarioFails(t, "invalid-flag", "datadiff: unknown flag type string (expected datadiff.Flag or datadiff.Option)")
 }
 
 func TestAssert_SliceOfNonStruct(t *testing.T) {
@@ -121,6 +128,87 @@ func TestAssert_OutputContainsTable(t *testing.T) {
 	}
 }
 
+var _ Reporter = (testing.TB)(nil)
+
+// fakeReporter records Assert output without failing the surrounding test.
+type fakeReporter struct {
+	helperCalls int
+	errors      []string
+	fatals      []string
+}
+
+func (r *fakeReporter) Helper() {
+	r.helperCalls++
+}
+
+func (r *fakeReporter) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *fakeReporter) Fatalf(format string, args ...any) {
+	r.fatals = append(r.fatals, fmt.Sprintf(format, args...))
+}
+
+func TestAssert_FakeReporter_Equal(t *testing.T) {
+	r := &fakeReporter{}
+	a := []Person{{Name: "Alice", Age: 30}}
+
+	if !Assert(r, a, a) {
+		t.Fatal("expected Assert to return true")
+	}
+	if r.helperCalls == 0 {
+		t.Fatal("expected Assert to call Helper")
+	}
+	if len(r.errors) != 0 || len(r.fatals) != 0 {
+		t.Fatalf("expected no reports, got errors=%q fatals=%q", r.errors, r.fatals)
+	}
+}
+
+func TestAssert_FakeReporter_Mismatch(t *testing.T) {
+	r := &fakeReporter{}
+	a := []Person{{Name: "Alice", Age: 30}}
+	b := []Person{{Name: "Alice", Age: 31}}
+
+	if Assert(r, a, b) {
+		t.Fatal("expected Assert to return false")
+	}
+	if len(r.fatals) != 0 {
+		t.Fatalf("expected no fatal reports, got %q", r.fatals)
+	}
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "datadiff: []Person are not equal") {
+		t.Fatalf("expected one diff error, got %q", r.errors)
+	}
+}
+
+func TestAssert_FakeReporter_Fatal(t *testing.T) {
+	r := &fakeReporter{}
+
+	if Assert(r, nil, []Person{}) {
+		t.Fatal("expected Assert to return false")
+	}
+	if len(r.errors) != 0 {
+		t.Fatalf("expected no error reports, got %q", r.errors)
+	}
+	if len(r.fatals) != 1 || !strings.Contains(r.fatals[0], "datadiff: first argument: datadiff: input is nil") {
+		t.Fatalf("expected one fatal report, got %q", r.fatals)
+	}
+}
+
+func BenchmarkAssert(b *testing.B) {
+	listA := make([]Person, 100)
+	listB := make([]Person, 100)
+	for i := range listA {
+		listA[i] = Person{Name: fmt.Sprintf("person-%d", i), Age: i}
+		listB[len(listB)-1-i] = listA[i]
+	}
+
+	for b.Loop() {
+		if !Assert(b, listA, listB, IgnoreOrder) {
+			b.Fatal("expected Assert to return true")
+		}
+	}
+}
+
 func assertScenarioFails(t *testing.T, scenario string, requiredSubstrings ...string) string {
 	t.Helper()
 
@@ -210,3 +298,500 @@ func TestAssert_SubprocessHarness(t *testing.T) {
 		t.Fatalf("unknown subprocess scenario %q", scenario)
 	}
 }
+
+func TestAssert_KeyColumns(t *testing.T) {
+	type User struct {
+		ID   int
+		City string
+		Age  int
+	}
+
+	a := []User{{ID: 1, City: "Boston", Age: 30}, {ID: 7, City: "Boston", Age: 30}}
+	b := []User{{ID: 7, City: "Boston", Age: 31}, {ID: 1, City: "Boston", Age: 30}}
+
+	r := &fakeReporter{}
+	if Assert(r, a, b, KeyColumns("ID")) {
+		t.Fatal("expected Assert to return false")
+	}
+	if len(r.errors) != 1 || !strings.Contains(stripANSI(r.errors[0]), "ID=7") {
+		t.Fatalf("expected diff labelled by key, got %q", r.errors)
+	}
+
+	b[0].Age = 30
+	if !Assert(t, a, b, KeyColumns("ID")) {
+		t.Fatal("expected Assert to return true when keyed rows match")
+	}
+}
+
+func TestAssert_KeyTag(t *testing.T) {
+	type User struct {
+		ID   int `datadiff:"key"`
+		Name string
+	}
+
+	a := []User{{ID: 1, Name: "Alice"}, {ID: 2, Name: "Bob"}}
+	b := []User{{ID: 2, Name: "Bob"}, {ID: 1, Name: "Alice"}}
+
+	if !Assert(t, a, b) {
+		t.Fatal("expected Assert to match rows by tagged key")
+	}
+}
+
+func TestAssert_UnknownKeyColumn(t *testing.T) {
+	r := &fakeReporter{}
+	if Assert(r, []Person{}, []Person{}, KeyColumns("ID")) {
+		t.Fatal("expected Assert to return false")
+	}
+	if len(r.fatals) != 1 || !strings.Contains(r.fatals[0], `key column "ID" not found in Person`) {
+		t.Fatalf("expected unknown key column fatal, got %q", r.fatals)
+	}
+}
+
+func TestAssert_OptimalMatching(t *testing.T) {
+	type Point struct {
+		X, Y, Z int
+	}
+
+	a := []Point{{0, 0, 0}, {1, 0, 2}}
+	b := []Point{{1, 0, 0}, {0, 1, 1}}
+
+	greedy, err := Diff(a, b, IgnoreOrder)
+	if err != nil {
+		t.Fatalf("Diff returned unexpected error: %v", err)
+	}
+	optimal, err := Diff(a, b, IgnoreOrder, OptimalMatching)
+	if err != nil {
+		t.Fatalf("Diff returned unexpected error: %v", err)
+	}
+
+	count := func(r Result) int {
+		total := 0
+		for _, row := range r.Rows() {
+			for _, mismatch := range row.Mismatch {
+				if mismatch {
+					total++
+				}
+			}
+		}
+		return total
+	}
+
+	if count(greedy) != 4 || count(optimal) != 3 {
+		t.Fatalf("mismatch counts: greedy=%d optimal=%d, want 4 and 3", count(greedy), count(optimal))
+	}
+}
+
+func TestAssert_IgnoreColumns(t *testing.T) {
+	a := []Person{{Name: "Alice", Age: 30}}
+	b := []Person{{Name: "Alice", Age: 31}}
+
+	if !Assert(t, a, b, IgnoreColumns("Age")) {
+		t.Fatal("expected Assert to return true when the differing column is ignored")
+	}
+	if !Assert(t, a, b, OnlyColumns("Name")) {
+		t.Fatal("expected Assert to return true when only matching columns are selected")
+	}
+
+	r := &fakeReporter{}
+	if Assert(r, a, b, OnlyColumns("Age")) {
+		t.Fatal("expected Assert to return false")
+	}
+	if len(r.errors) != 1 || strings.Contains(r.errors[0], "Name") {
+		t.Fatalf("expected diff table without the Name column, got %q", r.errors)
+	}
+}
+
+func TestAssert_UnknownColumn(t *testing.T) {
+	r := &fakeReporter{}
+	if Assert(r, []Person{}, []Person{}, IgnoreColumns("UpdatedAt")) {
+		t.Fatal("expected Assert to return false")
+	}
+	if len(r.fatals) != 1 || !strings.Contains(r.fatals[0], `datadiff: column "UpdatedAt" not found in Person`) {
+		t.Fatalf("expected unknown column fatal, got %q", r.fatals)
+	}
+}
+
+func TestAssert_Tolerance(t *testing.T) {
+	type Total struct {
+		Account string
+		Amount  float64
+		Rate    float64
+	}
+
+	x, y := 0.1, 0.2
+	a := []Total{{Account: "x", Amount: x + y, Rate: math.NaN()}}
+	b := []Total{{Account: "x", Amount: 0.3, Rate: math.NaN()}}
+
+	r := &fakeReporter{}
+	if Assert(r, a, b) {
+		t.Fatal("expected Assert to return false without tolerance")
+	}
+	if !Assert(t, a, b, WithTolerance(1e-9, 0), EquateNaN) {
+		t.Fatal("expected Assert to return true with tolerance and EquateNaN")
+	}
+	if !Assert(t, a, b, WithColumnTolerance("Amount", 0, 1e-9), EquateNaN) {
+		t.Fatal("expected Assert to return true with column tolerance and EquateNaN")
+	}
+
+	r = &fakeReporter{}
+	if Assert(r, a, b, WithColumnTolerance("Account", 1, 0)) {
+		t.Fatal("expected Assert to return false")
+	}
+	if len(r.fatals) != 1 || !strings.Contains(r.fatals[0], `tolerance column "Account" has non-float type string`) {
+		t.Fatalf("expected non-float column fatal, got %q", r.fatals)
+	}
+}
+
+func TestAssert_Comparers(t *testing.T) {
+	type Account struct {
+		Email   string
+		Balance *big.Int
+		Opened  time.Time
+	}
+
+	opened := time.Date(2026, time.January, 2, 9, 0, 0, 0, time.UTC)
+	a := []Account{{Email: "Alice@Example.com", Balance: big.NewInt(100), Opened: opened}}
+	b := []Account{{Email: "alice@example.com", Balance: big.NewInt(100), Opened: opened.Local()}}
+
+	r := &fakeReporter{}
+	if Assert(r, a, b) {
+		t.Fatal("expected Assert to return false without comparers")
+	}
+
+	ok := Assert(t, a, b,
+		WithColumnComparer("Email", strings.EqualFold),
+		WithComparer(func(x, y *big.Int) bool { return x.Cmp(y) == 0 }),
+	)
+	if !ok {
+		t.Fatal("expected Assert to return true with comparers")
+	}
+}
+
+func TestAssert_OptionConstructors(t *testing.T) {
+	a := []Person{{Name: "Alice", Age: 30}, {Name: "Bob", Age: 25}, {Name: "Charlie", Age: 35}}
+	b := []Person{{Name: "Bob", Age: 25}, {Name: "Alice", Age: 30}}
+
+	if !Assert(t, a, b, WithIgnoreOrder(), IgnoreLengths) {
+		t.Fatal("expected Assert to return true with mixed flags and options")
+	}
+}
+
+func TestAssert_ConflictingOptions(t *testing.T) {
+	r := &fakeReporter{}
+	if Assert(r, []Person{}, []Person{}, WithOptimalMatching()) {
+		t.Fatal("expected Assert to return false")
+	}
+	if len(r.fatals) != 1 || !strings.Contains(r.fatals[0], "datadiff: conflicting options: OptimalMatching requires IgnoreOrder") {
+		t.Fatalf("expected conflicting options fatal, got %q", r.fatals)
+	}
+}
+
+func TestAssert_NestedStructs(t *testing.T) {
+	type Address struct {
+		Street string
+		Zip    string
+	}
+	type Customer struct {
+		Name    string
+		Address Address
+	}
+
+	a := []Customer{{Name: "Alice", Address: Address{Street: "1 Main St", Zip: "10001"}}}
+	b := []Customer{{Name: "Alice", Address: Address{Street: "1 Main St", Zip: "10002"}}}
+
+	result, err := Diff(a, b)
+	if err != nil {
+		t.Fatalf("Diff returned unexpected error: %v", err)
+	}
+
+	wantColumns := []string{"Name", "Address.Street", "Address.Zip"}
+	if !reflect.DeepEqual(result.Columns(), wantColumns) {
+		t.Fatalf("columns mismatch: got %#v, want %#v", result.Columns(), wantColumns)
+	}
+	if !reflect.DeepEqual(result.Rows()[0].Mismatch, []bool{false, false, true}) {
+		t.Fatalf("expected mismatch only in Address.Zip, got %#v", result.Rows()[0].Mismatch)
+	}
+
+	result, err = Diff(a, b, WithMaxDepth(0))
+	if err != nil {
+		t.Fatalf("Diff returned unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(result.Columns(), []string{"Name", "Address"}) {
+		t.Fatalf("expected unflattened columns, got %#v", result.Columns())
+	}
+}
+
+func TestAssert_SliceOfPointers(t *testing.T) {
+	a := []*Person{{Name: "Alice", Age: 30}, nil}
+
+	if !Assert(t, a, []*Person{{Name: "Alice", Age: 30}, nil}) {
+		t.Fatal("expected Assert to return true for equal pointer slices")
+	}
+
+	result, err := Diff(a, []*Person{nil, {Name: "Bob", Age: 25}})
+	if err != nil {
+		t.Fatalf("Diff returned unexpected error: %v", err)
+	}
+	if result.Equal() {
+		t.Fatal("expected nil and non-nil rows to differ")
+	}
+
+	rows := result.Rows()
+	if rows[0].Status != RowMismatch || rows[0].ExpectedNil || !rows[0].ActualNil {
+		t.Fatalf("row 0 mismatch: got %+v", rows[0])
+	}
+	if !strings.Contains(result.String(), "<nil row>") {
+		t.Fatalf("expected <nil row> in output, got %q", result.String())
+	}
+
+	result, err = Diff(a, []*Person{{Name: "Alice", Age: 31}, nil}, IgnoreColumns("Age"))
+	if err != nil {
+		t.Fatalf("Diff returned unexpected error: %v", err)
+	}
+	if !result.Equal() {
+		t.Fatalf("expected nil rows to survive column selection, got:\n%s", result)
+	}
+}
+
+func TestAssert_ArraysIteratorsAndChannels(t *testing.T) {
+	people := []Person{{Name: "Alice", Age: 30}, {Name: "Bob", Age: 25}}
+
+	ch := make(chan Person, len(people))
+	for _, p := range people {
+		ch <- p
+	}
+	close(ch)
+
+	if !Assert(t, [2]Person{people[0], people[1]}, slices.Values(people)) {
+		t.Fatal("expected array and iter.Seq to compare equal")
+	}
+	if !Assert(t, people, ch) {
+		t.Fatal("expected slice and drained channel to compare equal")
+	}
+}
+
+func TestAssert_SliceOfMaps(t *testing.T) {
+	a := []map[string]any{
+		{"ID": 1, "Name": "Alice", "Email": "alice@example.com"},
+		{"ID": 2, "Name": "Bob"},
+	}
+	b := []map[string]any{
+		{"ID": 1, "Name": "Alice"},
+		{"ID": 2, "Name": "Bob", "Email": nil},
+	}
+
+	result, err := Diff(a, b)
+	if err != nil {
+		t.Fatalf("Diff returned unexpected error: %v", err)
+	}
+	if result.Equal() {
+		t.Fatal("expected differing key sets to be unequal")
+	}
+	if !reflect.DeepEqual(result.Columns(), []string{"Email", "ID", "Name"}) {
+		t.Fatalf("columns mismatch: got %#v", result.Columns())
+	}
+
+	rows := result.Rows()
+	if rows[0].Actual[0] != (MissingKey{}) || rows[1].Expected[0] != (MissingKey{}) || rows[1].Actual[0] != nil {
+		t.Fatalf("expected missing keys to be distinct from nil values, got %+v", rows)
+	}
+	if !strings.Contains(result.String(), "missing keys: Email") {
+		t.Fatalf("expected missing keys note, got %q", result.String())
+	}
+
+	if !Assert(t, a, b, OnlyColumns("Name", "ID")) {
+		t.Fatal("expected Assert to return true on the selected columns")
+	}
+}
+
+func TestAssert_CrossType(t *testing.T) {
+	type PersonDTO struct {
+		Name  string
+		Age   int
+		Phone string
+	}
+
+	a := []Person{{Name: "Alice", Age: 30}, {Name: "Bob", Age: 25}}
+	b := []PersonDTO{{Name: "Alice", Age: 30, Phone: "555"}, {Name: "Bob", Age: 26}}
+
+	result, err := Diff(a, b, CrossType)
+	if err != nil {
+		t.Fatalf("Diff returned unexpected error: %v", err)
+	}
+	if result.Equal() || result.TypeMismatch() {
+		t.Fatalf("expected an unequal cross-type result, got Equal()=%v TypeMismatch()=%v", result.Equal(), result.TypeMismatch())
+	}
+	if result.OtherTypeName() != "PersonDTO" {
+		t.Fatalf("other type name mismatch: got %q", result.OtherTypeName())
+	}
+	if !reflect.DeepEqual(result.Schema(), SchemaDiff{Added: []string{"Phone"}}) {
+		t.Fatalf("schema mismatch: got %+v", result.Schema())
+	}
+	if !reflect.DeepEqual(result.Rows()[1].Mismatch, []bool{false, true}) {
+		t.Fatalf("expected Age mismatch in row 1, got %#v", result.Rows()[1].Mismatch)
+	}
+
+	b[1].Age = 25
+	if !Assert(t, a, b, WithCrossType(), IgnoreColumns("Phone")) {
+		t.Fatal("expected Assert to return true once the one-sided column is ignored")
+	}
+}
+
+func TestAssert_CrossTypeStructAndMaps(t *testing.T) {
+	a := []Person{{Name: "Alice", Age: 30}, {Name: "Bob", Age: 25}}
+	b := []map[string]any{{"Name": "Bob", "Age": 25}, {"Name": "Alice", "Age": 30}}
+
+	if !Assert(t, a, b, CrossType, KeyColumns("Name")) {
+		t.Fatal("expected struct and map rows to compare equal by key")
+	}
+
+	r := &fakeReporter{}
+	Assert(r, a, b, CrossType, KeyColumns("Email"))
+	if len(r.fatals) != 1 || !strings.Contains(r.fatals[0], `key column "Email" not found in Person`) {
+		t.Fatalf("expected unknown key column fatal, got %q", r.fatals)
+	}
+}
+
+func TestAssert_CrossTypeJSON(t *testing.T) {
+	type Account struct {
+		ID      int
+		Name    string
+		Balance float32
+		Limit   uint
+	}
+	a := []Account{{ID: 1, Name: "Alice", Balance: 10.5, Limit: 100}, {ID: 2, Name: "Bob", Balance: 0, Limit: 50}}
+
+	var b []map[string]any
+	body := `[{"ID": 1, "Name": "Alice", "Balance": 10.5, "Limit": 100}, {"ID": 2, "Name": "Bob", "Balance": 0, "Limit": 50}]`
+	if err := json.Unmarshal([]byte(body), &b); err != nil {
+		t.Fatal(err)
+	}
+	if !Assert(t, a, b, CrossType) {
+		t.Fatal("expected numbers decoded as float64 to equal int, float32 and uint fields")
+	}
+	if !Assert(t, a, b, CrossType, IgnoreOrder) {
+		t.Fatal("expected numbers decoded as float64 to equal int, float32 and uint fields in any order")
+	}
+
+	b[1]["Limit"] = 51.0
+	result, err := Diff(a, b, CrossType, WithColor(ColorNever))
+	if err != nil {
+		t.Fatalf("Diff returned unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(result.Rows()[1].Mismatch, []bool{false, false, false, true}) {
+		t.Fatalf("expected only Limit to mismatch in row 1, got %#v", result.Rows()[1].Mismatch)
+	}
+	if out := result.String(); !strings.Contains(out, "*51* (+1)") {
+		t.Fatalf("expected delta between uint and float64 values, got %q", out)
+	}
+}
+
+func TestAssert_SchemaDrift(t *testing.T) {
+	a := []Person{{Name: "Alice", Age: 30}}
+
+	// A second version of Person: same type name, changed schema.
+	type Person struct {
+		Name  string
+		Age   int64
+		Email string
+	}
+	b := []Person{{Name: "Alice", Age: 30, Email: "alice@example.com"}}
+
+	result, err := Diff(a, b)
+	if err != nil {
+		t.Fatalf("Diff returned unexpected error: %v", err)
+	}
+	if result.Equal() || result.TypeMismatch() {
+		t.Fatalf("expected schema drift to be reported, got Equal()=%v TypeMismatch()=%v", result.Equal(), result.TypeMismatch())
+	}
+
+	want := SchemaDiff{
+		Added:   []string{"Email"},
+		Retyped: []RetypedColumn{{Name: "Age", Expected: reflect.TypeFor[int](), Actual: reflect.TypeFor[int64]()}},
+	}
+	if !reflect.DeepEqual(result.Schema(), want) {
+		t.Fatalf("schema mismatch: got %+v, want %+v", result.Schema(), want)
+	}
+	if out := result.String(); !strings.Contains(out, "int → int64") || !strings.Contains(out, "Email") {
+		t.Fatalf("expected schema section in output, got %q", out)
+	}
+	if rows := result.Rows(); len(rows) != 1 || rows[0].Status != RowMatch {
+		t.Fatalf("expected the retyped Age column to compare by value, got %+v", rows)
+	}
+}
+
+func TestAssert_KeyFunc(t *testing.T) {
+	type User struct {
+		Email    string
+		TenantID int
+		Name     string
+	}
+	byIdentity := WithKeyFunc(func(u User) string {
+		return fmt.Sprintf("%s+%d", strings.ToLower(u.Email), u.TenantID)
+	})
+
+	a := []User{
+		{Email: "alice@example.com", TenantID: 1, Name: "Alice"},
+		{Email: "bob@example.com", TenantID: 2, Name: "Bob"},
+	}
+	b := []User{
+		{Email: "bob@example.com", TenantID: 2, Name: "Robert"},
+		{Email: "alice@example.com", TenantID: 1, Name: "Alice"},
+		{Email: "carol@example.com", TenantID: 1, Name: "Carol"},
+	}
+
+	result, err := Diff(a, b, byIdentity)
+	if err != nil {
+		t.Fatalf("Diff returned unexpected error: %v", err)
+	}
+
+	wantKeys := []string{"key=alice@example.com+1", "key=bob@example.com+2", "key=carol@example.com+1"}
+	wantStatus := []RowStatus{RowMatch, RowMismatch, RowMissingKey}
+	for i, row := range result.Rows() {
+		if row.Key != wantKeys[i] || row.Status != wantStatus[i] {
+			t.Fatalf("row %d mismatch: got key %q status %v, want %q %v", i, row.Key, row.Status, wantKeys[i], wantStatus[i])
+		}
+	}
+	if !strings.Contains(result.String(), "key=bob@example.com+2") {
+		t.Fatalf("expected rows labelled by key value, got %q", result.String())
+	}
+
+	r := &fakeReporter{}
+	Assert(r, []Person{}, []Person{}, byIdentity)
+	if len(r.fatals) != 1 || !strings.Contains(r.fatals[0], "key function takes datadiff.User, but elements are datadiff.Person") {
+		t.Fatalf("expected element type fatal, got %q", r.fatals)
+	}
+}
+
+func TestAssert_CellFormatting(t *testing.T) {
+	type Event struct {
+		Name    string
+		At      time.Time
+		Payload []byte
+		Amount  *big.Int
+	}
+
+	at := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	a := []Event{{Name: "created", At: at, Payload: []byte("hi"), Amount: big.NewInt(10)}}
+	b := []Event{{Name: "updated", At: at, Payload: []byte("hi"), Amount: big.NewInt(10)}}
+
+	result, err := Diff(a, b,
+		WithTimeFormat(time.DateOnly),
+		WithBytesEncoding(BytesBase64),
+		WithFormatter(func(n *big.Int) string { return "$" + n.String() }),
+		WithComparer(func(x, y *big.Int) bool { return x.Cmp(y) == 0 }),
+	)
+	if err != nil {
+		t.Fatalf("Diff returned unexpected error: %v", err)
+	}
+
+	out := result.String()
+	for _, want := range []string{"2024-03-01", "aGk=", "$10"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in output, got %q", want, out)
+		}
+	}
+	if strings.Contains(out, "0x") {
+		t.Fatalf("expected no pointer addresses in output, got %q", out)
+	}
+}
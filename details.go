@@ -0,0 +1,395 @@
+package datadiff
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// detailContext is the number of unchanged lines shown around each change
+// in the details section.
+const detailContext = 3
+
+// maxPrettyDepth bounds how deeply [prettyPrinter.pretty] descends into
+// nested values.
+const maxPrettyDepth = 32
+
+// maxDetailLines bounds the number of lines each value takes in the
+// details section.
+const maxDetailLines = 1000
+
+// maxLineDiffCells bounds the size of the table used to diff two values
+// line by line; larger values are shown as a whole-value replacement.
+const maxLineDiffCells = 1 << 20
+
+// writeDetails writes the details section shown below the row table: a
+// unified line diff of each mismatched cell whose values are structs,
+// maps, slices, arrays, or strings too long or multi-line for the table.
+// Values that render identically get a note instead of an empty diff. It
+// writes nothing if there are no such cells.
+func writeDetails(b *strings.Builder, result diffResult) {
+	style := result.style
+	wroteHeader := false
+
+	for _, diff := range result.diffs {
+		if diff.status != rowMismatch || diff.nilA || diff.nilB {
+			continue
+		}
+		label := fmt.Sprintf("row %d", diff.index)
+		if diff.key != "" {
+			label = "row " + diff.key
+		}
+
+		for i, column := range result.columns {
+			if i >= len(diff.mismatch) || !diff.mismatch[i] || i >= len(diff.valuesA) || i >= len(diff.valuesB) {
+				continue
+			}
+			a, c := diff.valuesA[i], diff.valuesB[i]
+			if !style.needsDetail(a) && !style.needsDetail(c) {
+				continue
+			}
+
+			if !wroteHeader {
+				b.WriteString("\ndetails:\n")
+				wroteHeader = true
+			}
+			fmt.Fprintf(b, "\n%s, column %s:\n", label, column)
+
+			lines := unifiedDiff(style.cells.lines(a), style.cells.lines(c), detailContext)
+			if len(lines) == 0 {
+				b.WriteString("  values differ but render identically\n")
+				continue
+			}
+			b.WriteString("  " + style.colorize("--- expected", ansiRed) + "\n")
+			b.WriteString("  " + style.colorize("+++ actual", ansiGreen) + "\n")
+			for _, line := range lines {
+				switch line[0] {
+				case '-':
+					line = style.colorize(line, ansiRed)
+				case '+':
+					line = style.colorize(line, ansiGreen)
+				}
+				b.WriteString("  " + line + "\n")
+			}
+		}
+	}
+}
+
+// needsDetail reports whether a mismatched cell value is shown in the
+// details section: it is a struct, map, slice or array shown without a
+// formatter, Error or String method, or a string that is multi-line or
+// wider than the table's cells.
+func (s outputStyle) needsDetail(v any) bool {
+	if v == nil {
+		return false
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() && !s.cells.isLeaf(rv.Type()) {
+		rv = rv.Elem()
+	}
+	if s.cells.isLeaf(rv.Type()) {
+		return false
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return true
+	case reflect.String:
+		text := s.cells.format(v)
+		return strings.Contains(text, "\n") || s.maxWidth > 0 && displayWidth(text) > s.maxWidth
+	}
+	return false
+}
+
+// isLeaf reports whether values of typ are rendered whole by
+// [cellFormatter.format] rather than field by field: those with a
+// registered formatter or an Error or String method, and byte slices.
+func (f cellFormatter) isLeaf(typ reflect.Type) bool {
+	return f.formatterFor(typ) != nil || typ.Implements(errorType) || typ.Implements(stringerType) ||
+		typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8
+}
+
+// lines returns v rendered for the details section, split into lines.
+// Strings are shown verbatim and other values are pretty-printed. At most
+// maxDetailLines lines are returned; a final ellipsis line marks the rest
+// as left out.
+func (f cellFormatter) lines(v any) []string {
+	var lines []string
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.String && !f.isLeaf(rv.Type()) {
+		lines = strings.Split(rv.String(), "\n")
+	} else {
+		p := prettyPrinter{cells: f, path: make(map[prettyVisit]bool)}
+		p.pretty(reflect.ValueOf(v), "", 0)
+		lines = strings.Split(p.b.String(), "\n")
+	}
+	if len(lines) > maxDetailLines {
+		lines = append(lines[:maxDetailLines], ellipsis)
+	}
+	return lines
+}
+
+// prettyPrinter renders a value for [cellFormatter.lines].
+type prettyPrinter struct {
+	cells cellFormatter
+	b     strings.Builder
+	lines int // lines written so far
+
+	// path holds the pointers, maps and slices being rendered, from the
+	// root down to the current value, to detect cycles.
+	path map[prettyVisit]bool
+}
+
+// prettyVisit identifies a pointer, map or slice on the current path. The
+// type and length tell apart values that share an address, such as a
+// struct and its first field, or a slice and a shorter slice of it.
+type prettyVisit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// pretty writes rv in a Go-like syntax with one field, map entry or
+// element per line, each indented two spaces more than its parent.
+// Pointers are dereferenced, values [cellFormatter.isLeaf] accepts are
+// rendered with [cellFormatter.format], and a value that contains itself
+// is shown as "<cycle>" where it repeats. Output stops after more than
+// maxDetailLines lines.
+func (p *prettyPrinter) pretty(rv reflect.Value, indent string, depth int) {
+	if p.lines > maxDetailLines {
+		return
+	}
+	if !rv.IsValid() {
+		p.b.WriteString("nil")
+		return
+	}
+	if depth > maxPrettyDepth {
+		p.b.WriteString(ellipsis)
+		return
+	}
+	if rv.CanInterface() && p.cells.isLeaf(rv.Type()) {
+		p.b.WriteString(p.cells.format(rv.Interface()))
+		return
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		if rv.IsNil() {
+			p.b.WriteString("nil")
+			return
+		}
+		visit := prettyVisit{ptr: rv.Pointer(), typ: rv.Type()}
+		if rv.Kind() == reflect.Slice {
+			visit.len = rv.Len()
+		}
+		if p.path[visit] {
+			p.b.WriteString("<cycle>")
+			return
+		}
+		p.path[visit] = true
+		defer delete(p.path, visit)
+	}
+
+	inner := indent + "  "
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			p.b.WriteString("nil")
+			return
+		}
+		p.pretty(rv.Elem(), indent, depth+1)
+	case reflect.String:
+		p.b.WriteString(strconv.Quote(rv.String()))
+	case reflect.Struct:
+		p.b.WriteString(prettyTypeName(rv.Type()) + "{")
+		if rv.NumField() == 0 {
+			p.b.WriteString("}")
+			return
+		}
+		p.newline()
+		for i := range rv.NumField() {
+			p.b.WriteString(inner + rv.Type().Field(i).Name + ": ")
+			p.pretty(rv.Field(i), inner, depth+1)
+			p.b.WriteString(",")
+			p.newline()
+		}
+		p.b.WriteString(indent + "}")
+	case reflect.Map:
+		p.b.WriteString(prettyTypeName(rv.Type()) + "{")
+		if rv.Len() == 0 {
+			p.b.WriteString("}")
+			return
+		}
+		type entry struct {
+			key   string
+			value reflect.Value
+		}
+		entries := make([]entry, 0, rv.Len())
+		for iter := rv.MapRange(); iter.Next(); {
+			key := prettyPrinter{cells: p.cells, path: p.path}
+			key.pretty(iter.Key(), inner, depth+1)
+			entries = append(entries, entry{key: key.b.String(), value: iter.Value()})
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+		p.newline()
+		for _, e := range entries {
+			p.b.WriteString(inner + e.key + ": ")
+			p.pretty(e.value, inner, depth+1)
+			p.b.WriteString(",")
+			p.newline()
+		}
+		p.b.WriteString(indent + "}")
+	case reflect.Slice, reflect.Array:
+		p.b.WriteString(prettyTypeName(rv.Type()) + "{")
+		if rv.Len() == 0 {
+			p.b.WriteString("}")
+			return
+		}
+		p.newline()
+		for i := range rv.Len() {
+			p.b.WriteString(inner)
+			p.pretty(rv.Index(i), inner, depth+1)
+			p.b.WriteString(",")
+			p.newline()
+		}
+		p.b.WriteString(indent + "}")
+	default:
+		fmt.Fprintf(&p.b, "%v", rv)
+	}
+}
+
+// newline ends the current line.
+func (p *prettyPrinter) newline() {
+	p.b.WriteByte('\n')
+	p.lines++
+}
+
+// prettyTypeName returns the name of t as shown by [prettyPrinter.pretty].
+func prettyTypeName(t reflect.Type) string {
+	return strings.ReplaceAll(t.String(), "interface {}", "any")
+}
+
+// unifiedDiff returns a line diff of a and b in unified format: hunks
+// headed "@@ -start,count +start,count @@", each listing changed lines
+// prefixed "-" or "+" with up to context unchanged lines, prefixed " ",
+// around them.
+func unifiedDiff(a, b []string, context int) []string {
+	ops := lineDiff(a, b)
+
+	var out []string
+	for start := 0; start < len(ops); {
+		// Find the next change and the end of its hunk: the point where
+		// more than twice the context of unchanged lines follows.
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		end := first
+		for end < len(ops) {
+			next := end
+			for next < len(ops) && ops[next].kind != ' ' {
+				next++
+			}
+			same := next
+			for same < len(ops) && ops[same].kind == ' ' {
+				same++
+			}
+			end = next
+			if same == len(ops) || same-next > 2*context {
+				break
+			}
+			end = same
+		}
+
+		from := max(first-context, start)
+		to := min(end+context, len(ops))
+		hunk := ops[from:to]
+
+		lineA, lineB := ops[from].lineA, ops[from].lineB
+		countA, countB := 0, 0
+		for _, op := range hunk {
+			if op.kind != '+' {
+				countA++
+			}
+			if op.kind != '-' {
+				countB++
+			}
+		}
+		out = append(out, fmt.Sprintf("@@ -%s +%s @@", hunkRange(lineA, countA), hunkRange(lineB, countB)))
+		for _, op := range hunk {
+			out = append(out, string(op.kind)+op.text)
+		}
+		start = to
+	}
+	return out
+}
+
+// hunkRange formats the line range of one side of a hunk, where line is
+// the 0-based index of its first line.
+func hunkRange(line, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", line)
+	}
+	return fmt.Sprintf("%d,%d", line+1, count)
+}
+
+// lineOp is one step of a line diff: an unchanged (' '), removed ('-') or
+// added ('+') line, with the 0-based position it occurs at in each input.
+type lineOp struct {
+	kind         byte
+	text         string
+	lineA, lineB int
+}
+
+// lineDiff returns a shortest edit script turning a into b, computed from
+// their longest common subsequence of lines. Inputs too large to diff are
+// treated as entirely replaced.
+func lineDiff(a, b []string) []lineOp {
+	var ops []lineOp
+	if (len(a)+1)*(len(b)+1) > maxLineDiffCells {
+		for i, line := range a {
+			ops = append(ops, lineOp{kind: '-', text: line, lineA: i, lineB: 0})
+		}
+		for j, line := range b {
+			ops = append(ops, lineOp{kind: '+', text: line, lineA: len(a), lineB: j})
+		}
+		return ops
+	}
+
+	// common[i][j] is the length of the longest common subsequence of
+	// a[i:] and b[j:].
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, lineOp{kind: ' ', text: a[i], lineA: i, lineB: j})
+			i++
+			j++
+		case j == len(b) || i < len(a) && common[i+1][j] >= common[i][j+1]:
+			ops = append(ops, lineOp{kind: '-', text: a[i], lineA: i, lineB: j})
+			i++
+		default:
+			ops = append(ops, lineOp{kind: '+', text: b[j], lineA: i, lineB: j})
+			j++
+		}
+	}
+	return ops
+}
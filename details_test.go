@@ -0,0 +1,271 @@
+package datadiff
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		want []string
+	}{
+		{
+			name: "equal",
+			a:    []string{"a", "b"},
+			b:    []string{"a", "b"},
+			want: nil,
+		},
+		{
+			name: "replacement",
+			a:    []string{"{", "a", "b", "}"},
+			b:    []string{"{", "a", "c", "}"},
+			want: []string{"@@ -1,4 +1,4 @@", " {", " a", "-b", "+c", " }"},
+		},
+		{
+			name: "insertion at end",
+			a:    []string{"1", "2", "3", "4", "5"},
+			b:    []string{"1", "2", "3", "4", "5", "6"},
+			want: []string{"@@ -3,3 +3,4 @@", " 3", " 4", " 5", "+6"},
+		},
+		{
+			name: "insertion into empty",
+			a:    nil,
+			b:    []string{"x"},
+			want: []string{"@@ -0,0 +1,1 @@", "+x"},
+		},
+		{
+			name: "separate hunks",
+			a:    []string{"a", "1", "2", "3", "4", "5", "6", "7", "8", "b"},
+			b:    []string{"A", "1", "2", "3", "4", "5", "6", "7", "8", "B"},
+			want: []string{
+				"@@ -1,4 +1,4 @@", "-a", "+A", " 1", " 2", " 3",
+				"@@ -7,4 +7,4 @@", " 6", " 7", " 8", "-b", "+B",
+			},
+		},
+		{
+			name: "merged hunks",
+			a:    []string{"a", "1", "2", "3", "4", "5", "6", "b"},
+			b:    []string{"A", "1", "2", "3", "4", "5", "6", "B"},
+			want: []string{"@@ -1,8 +1,8 @@", "-a", "+A", " 1", " 2", " 3", " 4", " 5", " 6", "-b", "+B"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := unifiedDiff(tt.a, tt.b, 3)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("unifiedDiff mismatch:\ngot  %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCellFormatter_Lines(t *testing.T) {
+	type Point struct{ X, Y int }
+	type Shape struct {
+		Name   string
+		Points []Point
+		Attrs  map[string]any
+		Parent *Shape
+		Since  time.Time
+	}
+
+	shape := Shape{
+		Name:   "tri",
+		Points: []Point{{0, 0}, {1, 2}},
+		Attrs:  map[string]any{"fill": "red", "alpha": 0.5},
+		Since:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	want := []string{
+		"datadiff.Shape{",
+		`  Name: "tri",`,
+		"  Points: []datadiff.Point{",
+		"    datadiff.Point{",
+		"      X: 0,",
+		"      Y: 0,",
+		"    },",
+		"    datadiff.Point{",
+		"      X: 1,",
+		"      Y: 2,",
+		"    },",
+		"  },",
+		"  Attrs: map[string]any{",
+		`    "alpha": 0.5,`,
+		`    "fill": "red",`,
+		"  },",
+		"  Parent: nil,",
+		"  Since: 2024-01-02T03:04:05Z,",
+		"}",
+	}
+
+	var f cellFormatter
+	if got := f.lines(&shape); !reflect.DeepEqual(got, want) {
+		t.Fatalf("lines mismatch:\ngot  %q\nwant %q", got, want)
+	}
+	if got := f.lines("one\ntwo"); !reflect.DeepEqual(got, []string{"one", "two"}) {
+		t.Fatalf("expected strings to be split verbatim, got %q", got)
+	}
+
+	shape.Parent = &shape
+	if got := strings.Join(f.lines(shape), "\n"); !strings.Contains(got, "  Parent: datadiff.Shape{") || !strings.Contains(got, "    Parent: <cycle>,") {
+		t.Fatalf("expected the repeated pointer to be marked as a cycle, got:\n%s", got)
+	}
+}
+
+func TestCellFormatter_LinesCycles(t *testing.T) {
+	type DNode struct {
+		Value      int
+		Prev, Next *DNode
+	}
+	type Tri struct {
+		A, B, C *Tri
+	}
+
+	// A circular doubly-linked list of three nodes.
+	first, second, third := &DNode{Value: 1}, &DNode{Value: 2}, &DNode{Value: 3}
+	first.Prev, first.Next = third, second
+	second.Prev, second.Next = first, third
+	third.Prev, third.Next = second, first
+
+	tri := &Tri{}
+	tri.A, tri.B, tri.C = tri, tri, tri
+
+	self := map[string]any{"id": 1}
+	self["self"] = self
+
+	list := []any{1, nil}
+	list[1] = list
+
+	var f cellFormatter
+	for name, value := range map[string]any{"list": first, "three self-pointers": tri, "map": self, "slice": list} {
+		t.Run(name, func(t *testing.T) {
+			lines := f.lines(value)
+			if len(lines) > 100 {
+				t.Fatalf("expected a short rendering, got %d lines", len(lines))
+			}
+			if !strings.Contains(strings.Join(lines, "\n"), "<cycle>") {
+				t.Fatalf("expected a cycle marker, got:\n%s", strings.Join(lines, "\n"))
+			}
+		})
+	}
+
+	want := []string{
+		"datadiff.DNode{",
+		"  Value: 1,",
+		"  Prev: datadiff.DNode{",
+		"    Value: 3,",
+		"    Prev: datadiff.DNode{",
+		"      Value: 2,",
+		"      Prev: <cycle>,",
+		"      Next: <cycle>,",
+		"    },",
+		"    Next: <cycle>,",
+		"  },",
+		"  Next: datadiff.DNode{",
+		"    Value: 2,",
+		"    Prev: <cycle>,",
+		"    Next: datadiff.DNode{",
+		"      Value: 3,",
+		"      Prev: <cycle>,",
+		"      Next: <cycle>,",
+		"    },",
+		"  },",
+		"}",
+	}
+	if got := f.lines(first); !reflect.DeepEqual(got, want) {
+		t.Fatalf("lines mismatch:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestCellFormatter_LinesLimit(t *testing.T) {
+	var f cellFormatter
+	lines := f.lines(make([]int, 5000))
+	if len(lines) != maxDetailLines+1 || lines[maxDetailLines] != ellipsis {
+		t.Fatalf("expected %d lines ending in an ellipsis, got %d lines ending in %q", maxDetailLines+1, len(lines), lines[len(lines)-1])
+	}
+}
+
+func TestOutputStyle_NeedsDetail(t *testing.T) {
+	style := outputStyle{maxWidth: 10}
+	tests := []struct {
+		name  string
+		value any
+		want  bool
+	}{
+		{name: "nil", value: nil, want: false},
+		{name: "int", value: 7, want: false},
+		{name: "short string", value: "Boston", want: false},
+		{name: "long string", value: "Massachusetts Avenue", want: true},
+		{name: "multi-line string", value: "a\nb", want: true},
+		{name: "struct", value: struct{ A int }{1}, want: true},
+		{name: "pointer to struct", value: &struct{ A int }{1}, want: true},
+		{name: "map", value: map[string]int{"a": 1}, want: true},
+		{name: "slice", value: []int{1}, want: true},
+		{name: "bytes", value: []byte("abc"), want: false},
+		{name: "time", value: time.Now(), want: false},
+		{name: "missing key", value: MissingKey{}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := style.needsDetail(tt.value); got != tt.want {
+				t.Fatalf("needsDetail(%#v) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+
+	style.cells.formatters = []typedFormatter{{typ: reflect.TypeFor[[]int](), format: func(any) string { return "ints" }}}
+	if style.needsDetail([]int{1}) {
+		t.Fatal("expected a value with a registered formatter to stay in the table")
+	}
+}
+
+func TestFormatDiff_DetailsCycle(t *testing.T) {
+	type Node struct {
+		Name     string
+		Parent   *Node
+		Children []*Node
+	}
+	type Row struct {
+		ID   int
+		Tree *Node
+	}
+	tree := func(child string) *Node {
+		root := &Node{Name: "root"}
+		root.Children = []*Node{{Name: child, Parent: root}}
+		root.Parent = root
+		return root
+	}
+
+	result, err := Diff([]Row{{ID: 1, Tree: tree("a")}}, []Row{{ID: 1, Tree: tree("b")}}, WithColor(ColorNever), WithMaxDepth(0))
+	if err != nil {
+		t.Fatalf("Diff returned unexpected error: %v", err)
+	}
+	got := result.String()
+	if len(got) > 4096 || !strings.Contains(got, `-      Name: "a",`) || !strings.Contains(got, `+      Name: "b",`) {
+		t.Fatalf("expected a short details section, got %d bytes:\n%s", len(got), got)
+	}
+}
+
+func TestFormatDiff_DetailsIdenticalRendering(t *testing.T) {
+	type Row struct {
+		ID      int
+		Weights map[string]float64
+	}
+	a := []Row{{ID: 1, Weights: map[string]float64{"x": math.NaN()}}}
+	b := []Row{{ID: 1, Weights: map[string]float64{"x": math.NaN()}}}
+
+	result, err := Diff(a, b, WithColor(ColorNever))
+	if err != nil {
+		t.Fatalf("Diff returned unexpected error: %v", err)
+	}
+	got := result.String()
+	if !strings.Contains(got, "row 0, column Weights:\n  values differ but render identically\n") || strings.Contains(got, "--- expected") {
+		t.Fatalf("expected a note instead of an empty diff, got:\n%s", got)
+	}
+}
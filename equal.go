@@ -0,0 +1,439 @@
+package datadiff
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// tolerance bounds the difference at which two floats are still equal.
+// A zero tolerance means exact comparison.
+type tolerance struct {
+	abs float64 // absolute bound on |a-b|
+	rel float64 // bound on |a-b| relative to max(|a|, |b|)
+}
+
+// matcher decides cell and row equality for one comparison.
+//
+// Cell values are compared with, in order of precedence: a column
+// comparer, a type comparer, the type's Equal method, float tolerance,
+// and finally a deep comparison that applies the same rules to what
+// pointers, slices, arrays, maps and structs hold.
+type matcher struct {
+	columns    []string
+	tolerances []tolerance           // per column
+	overrides  []func(a, b any) bool // per column comparer, or nil
+	nanEqual   bool
+
+	comparers []typedComparer
+	byType    map[reflect.Type]func(a, b any) bool // resolved type comparers; nil entries mean none
+
+	rowEqual func(a, b any) bool // whole-row comparer on elements, or nil
+
+	// byValue compares numbers, strings and booleans of different types
+	// by value, for lists of different element types such as a struct
+	// with int fields and JSON-decoded maps holding float64, or two
+	// versions of a struct with retyped columns.
+	byValue bool
+}
+
+// newMatcher resolves the equality rules in opts for each of columns.
+func newMatcher(columns []string, opts options) *matcher {
+	m := &matcher{
+		columns:    columns,
+		tolerances: make([]tolerance, len(columns)),
+		overrides:  make([]func(a, b any) bool, len(columns)),
+		nanEqual:   opts.nanEqual,
+		comparers:  opts.comparers,
+		byType:     make(map[reflect.Type]func(a, b any) bool),
+		rowEqual:   opts.rowEqual,
+	}
+
+	for i, column := range columns {
+		m.tolerances[i] = opts.tolerance
+		if columnTolerance, ok := opts.columnTolerances[column]; ok {
+			m.tolerances[i] = columnTolerance
+		}
+		if comparer, ok := opts.columnComparers[column]; ok {
+			m.overrides[i] = comparer.equal
+		}
+	}
+
+	return m
+}
+
+// rowMismatch compares two rows. A nil row equals only another nil row;
+// against a non-nil row every column counts as mismatched. With a row
+// comparer, it alone decides equality; the columns that differ are still
+// flagged, or all of them if none does.
+func (m *matcher) rowMismatch(a, b row) ([]bool, int) {
+	switch {
+	case a.isNil && b.isNil:
+		return make([]bool, len(m.columns)), 0
+	case a.isNil || b.isNil:
+		return m.allMismatched()
+	case m.rowEqual == nil:
+		return m.fieldMismatch(a.values, b.values)
+	case m.rowEqual(a.elem, b.elem):
+		return make([]bool, len(m.columns)), 0
+	}
+
+	if mismatch, mismatchCount := m.fieldMismatch(a.values, b.values); mismatchCount > 0 {
+		return mismatch, mismatchCount
+	}
+	return m.allMismatched()
+}
+
+// allMismatched flags every column as mismatched.
+func (m *matcher) allMismatched() ([]bool, int) {
+	mismatch := make([]bool, len(m.columns))
+	for i := range mismatch {
+		mismatch[i] = true
+	}
+	return mismatch, len(mismatch)
+}
+
+// fieldMismatch compares two rows column by column and returns the
+// per-column mismatch flags and the number of mismatched columns.
+func (m *matcher) fieldMismatch(valuesA, valuesB []any) ([]bool, int) {
+	mismatch := make([]bool, len(m.columns))
+	mismatchCount := 0
+
+	for i := range m.columns {
+		if i >= len(valuesA) || i >= len(valuesB) {
+			mismatch[i] = true
+			mismatchCount++
+			continue
+		}
+
+		if m.equal(i, valuesA[i], valuesB[i]) {
+			continue
+		}
+
+		mismatch[i] = true
+		mismatchCount++
+	}
+
+	return mismatch, mismatchCount
+}
+
+// equal reports whether two values of the given column are equal.
+func (m *matcher) equal(column int, a, b any) bool {
+	if override := m.overrides[column]; override != nil {
+		return override(a, b)
+	}
+	return m.deepEqual(m.tolerances[column], reflect.ValueOf(a), reflect.ValueOf(b), nil)
+}
+
+// visit is a pair of pointers, maps or slices being compared by
+// [matcher.deepEqual], recorded to stop at cycles.
+type visit struct {
+	a, b uintptr
+	typ  reflect.Type
+}
+
+// deepEqual reports whether a and b are deeply equal, like
+// reflect.DeepEqual, except that values of a type with a comparer or an
+// Equal method are compared with it, and floats are compared with tol
+// and EquateNaN. Non-nil pointers are dereferenced and slices, arrays,
+// maps and structs are walked element by element with the same rules, so
+// that *time.Time and []time.Time values use time.Time's Equal method.
+func (m *matcher) deepEqual(tol tolerance, a, b reflect.Value, visited map[visit]bool) bool {
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+	if a.Type() != b.Type() {
+		if m.byValue {
+			if equal, ok := m.scalarEqual(tol, a, b); ok {
+				return equal
+			}
+		}
+		return false
+	}
+
+	typ := a.Type()
+	if a.CanInterface() && b.CanInterface() {
+		if typeEqual := m.typeEqual(typ); typeEqual != nil {
+			return typeEqual(a.Interface(), b.Interface())
+		}
+	}
+
+	// Like reflect.DeepEqual, a pair of references already being compared
+	// further up is taken to be equal, which stops at cycles.
+	switch typ.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() && b.IsNil()
+		}
+		v := visit{a: a.Pointer(), b: b.Pointer(), typ: typ}
+		if visited[v] {
+			return true
+		}
+		if visited == nil {
+			visited = make(map[visit]bool)
+		}
+		visited[v] = true
+	}
+
+	switch typ.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return m.floatEqual(tol, a.Float(), b.Float())
+	case reflect.Pointer, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() && b.IsNil()
+		}
+		return m.deepEqual(tol, a.Elem(), b.Elem(), visited)
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := range a.Len() {
+			if !m.deepEqual(tol, a.Index(i), b.Index(i), visited) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for iter := a.MapRange(); iter.Next(); {
+			valueB := b.MapIndex(iter.Key())
+			if !valueB.IsValid() || !m.deepEqual(tol, iter.Value(), valueB, visited) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i := range a.NumField() {
+			if !m.deepEqual(tol, a.Field(i), b.Field(i), visited) {
+				return false
+			}
+		}
+		return true
+	case reflect.Func:
+		return a.IsNil() && b.IsNil()
+	default:
+		return a.Equal(b)
+	}
+}
+
+// typeEqual returns the equality function for values of typ: a registered
+// comparer or the type's Equal method. It returns nil if there is neither.
+func (m *matcher) typeEqual(typ reflect.Type) func(a, b any) bool {
+	if typeEqual, ok := m.byType[typ]; ok {
+		return typeEqual
+	}
+
+	var typeEqual func(a, b any) bool
+	for i := len(m.comparers) - 1; i >= 0 && typeEqual == nil; i-- {
+		if m.comparers[i].typ == typ {
+			typeEqual = m.comparers[i].equal
+		}
+	}
+	for i := len(m.comparers) - 1; i >= 0 && typeEqual == nil; i-- {
+		if m.comparers[i].typ.Kind() == reflect.Interface && typ.Implements(m.comparers[i].typ) {
+			typeEqual = m.comparers[i].equal
+		}
+	}
+	if typeEqual == nil {
+		typeEqual = equalMethod(typ)
+	}
+
+	m.byType[typ] = typeEqual
+	return typeEqual
+}
+
+// equalMethod returns a function calling the method Equal(T) bool of
+// typ, as go-cmp does, or nil if typ has no such method. Nil pointers are
+// equal to each other and never passed to the method.
+func equalMethod(typ reflect.Type) func(a, b any) bool {
+	method, ok := typ.MethodByName("Equal")
+	if !ok {
+		return nil
+	}
+
+	methodType := method.Type
+	if methodType.NumIn() != 2 || methodType.NumOut() != 1 ||
+		!typ.AssignableTo(methodType.In(1)) || methodType.Out(0).Kind() != reflect.Bool {
+		return nil
+	}
+
+	return func(a, b any) bool {
+		va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+		if typ.Kind() == reflect.Pointer && (va.IsNil() || vb.IsNil()) {
+			return va.IsNil() && vb.IsNil()
+		}
+		return method.Func.Call([]reflect.Value{va, vb})[0].Bool()
+	}
+}
+
+// scalarEqual compares a and b by value if both are strings, both are
+// booleans, or both are integers or floats, of any size or signedness.
+// Integers are compared exactly; a float and another number are compared
+// as float64, with tol and EquateNaN. It returns false as its second
+// result for other values.
+func (m *matcher) scalarEqual(tol tolerance, a, b reflect.Value) (bool, bool) {
+	switch {
+	case a.Kind() == reflect.String && b.Kind() == reflect.String:
+		return a.String() == b.String(), true
+	case a.Kind() == reflect.Bool && b.Kind() == reflect.Bool:
+		return a.Bool() == b.Bool(), true
+	}
+
+	kindA, kindB := numberKind(a.Kind()), numberKind(b.Kind())
+	if kindA == 0 || kindB == 0 {
+		return false, false
+	}
+
+	switch {
+	case kindA == 'i' && kindB == 'i':
+		return a.Int() == b.Int(), true
+	case kindA == 'u' && kindB == 'u':
+		return a.Uint() == b.Uint(), true
+	case kindA == 'i' && kindB == 'u':
+		return a.Int() >= 0 && uint64(a.Int()) == b.Uint(), true
+	case kindA == 'u' && kindB == 'i':
+		return b.Int() >= 0 && uint64(b.Int()) == a.Uint(), true
+	}
+	return m.floatEqual(tol, numberFloat(a), numberFloat(b)), true
+}
+
+// numberKind classifies k as a signed ('i') or unsigned ('u') integer or
+// a float ('f'), or returns 0 if it is not a number.
+func numberKind(k reflect.Kind) byte {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return 'i'
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return 'u'
+	case reflect.Float32, reflect.Float64:
+		return 'f'
+	}
+	return 0
+}
+
+// numberFloat returns the number v as a float64.
+func numberFloat(v reflect.Value) float64 {
+	switch numberKind(v.Kind()) {
+	case 'i':
+		return float64(v.Int())
+	case 'u':
+		return float64(v.Uint())
+	}
+	return v.Float()
+}
+
+func (m *matcher) floatEqual(tol tolerance, a, b float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return m.nanEqual && math.IsNaN(a) && math.IsNaN(b)
+	}
+	if a == b {
+		return true
+	}
+
+	delta := math.Abs(a - b)
+	if delta <= tol.abs {
+		return true
+	}
+	return delta <= tol.rel*math.Max(math.Abs(a), math.Abs(b))
+}
+
+// floatValue returns v as a float64 if its kind is float32 or float64.
+func floatValue(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+// numericDelta formats b-a, such as "+1" or "-0.25", when a and b are
+// numbers. time.Duration values and numbers of a type with a registered
+// formatter show the delta as a value of that type, such as "+1s". It
+// returns false for other values, for deltas the type cannot hold, and
+// for other types with an Error or String method, which are often
+// enumerations such as time.Month whose differences mean nothing.
+func numericDelta(a, b any, cells cellFormatter) (string, bool) {
+	if a == nil || b == nil {
+		return "", false
+	}
+
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Type() != vb.Type() {
+		return mixedNumericDelta(va, vb, cells)
+	}
+	typ := va.Type()
+	custom := typ == durationType || cells.formatterFor(typ) != nil
+	if !custom && cells.isLeaf(typ) {
+		return "", false
+	}
+
+	var delta reflect.Value
+	sign := "+"
+	switch va.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		d := vb.Int() - va.Int()
+		if !custom {
+			return fmt.Sprintf("%+d", d), true
+		}
+		if reflect.Zero(typ).OverflowInt(d) {
+			return "", false
+		}
+		if d < 0 {
+			sign = ""
+		}
+		delta = reflect.ValueOf(d).Convert(typ)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		d := vb.Uint() - va.Uint()
+		if vb.Uint() < va.Uint() {
+			d, sign = va.Uint()-vb.Uint(), "-"
+		}
+		if !custom {
+			return sign + strconv.FormatUint(d, 10), true
+		}
+		delta = reflect.ValueOf(d).Convert(typ)
+	case reflect.Float32, reflect.Float64:
+		d := vb.Float() - va.Float()
+		if math.IsNaN(d) || math.IsInf(d, 0) {
+			return "", false
+		}
+		if d < 0 {
+			sign = ""
+		}
+		if !custom {
+			return sign + strconv.FormatFloat(d, 'g', -1, typ.Bits()), true
+		}
+		if reflect.Zero(typ).OverflowFloat(d) {
+			return "", false
+		}
+		delta = reflect.ValueOf(d).Convert(typ)
+	default:
+		return "", false
+	}
+	return sign + cells.format(delta.Interface()), true
+}
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// mixedNumericDelta formats b-a for numbers a and b of different types,
+// such as an int and a float64 decoded from JSON, as a float64. It
+// returns false if either is not a plain number.
+func mixedNumericDelta(a, b reflect.Value, cells cellFormatter) (string, bool) {
+	if numberKind(a.Kind()) == 0 || numberKind(b.Kind()) == 0 || cells.isLeaf(a.Type()) || cells.isLeaf(b.Type()) {
+		return "", false
+	}
+	d := numberFloat(b) - numberFloat(a)
+	if math.IsNaN(d) || math.IsInf(d, 0) {
+		return "", false
+	}
+	formatted := strconv.FormatFloat(d, 'g', -1, 64)
+	if d >= 0 {
+		formatted = "+" + formatted
+	}
+	return formatted, true
+}
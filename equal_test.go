@@ -0,0 +1,340 @@
+package datadiff
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatcher_DefaultIsExact(t *testing.T) {
+	m := newMatcher([]string{"Value"}, options{})
+	x, y := 0.1, 0.2
+
+	if m.equal(0, x+y, 0.3) {
+		t.Fatal("expected 0.1+0.2 != 0.3 without tolerance")
+	}
+	if m.equal(0, math.NaN(), math.NaN()) {
+		t.Fatal("expected NaN != NaN without EquateNaN")
+	}
+	if !m.equal(0, "a", "a") || m.equal(0, "a", "b") {
+		t.Fatal("expected non-float values to use deep equality")
+	}
+}
+
+func TestMatcher_Tolerance(t *testing.T) {
+	x, y := 0.1, 0.2
+	tests := []struct {
+		name string
+		tol  tolerance
+		a, b any
+		want bool
+	}{
+		{name: "abs within", tol: tolerance{abs: 1e-9}, a: x + y, b: 0.3, want: true},
+		{name: "abs outside", tol: tolerance{abs: 0.01}, a: 1.0, b: 1.1, want: false},
+		{name: "rel within", tol: tolerance{rel: 0.01}, a: 1000.0, b: 1009.0, want: true},
+		{name: "rel outside", tol: tolerance{rel: 0.01}, a: 1.0, b: 1.1, want: false},
+		{name: "float32", tol: tolerance{abs: 0.01}, a: float32(1.0), b: float32(1.005), want: true},
+		{name: "mixed float types", tol: tolerance{abs: 1}, a: float32(1.0), b: 1.0, want: false},
+		{name: "ints unaffected", tol: tolerance{abs: 1}, a: 1, b: 2, want: false},
+		{name: "infinity", tol: tolerance{abs: 1}, a: math.Inf(1), b: math.Inf(1), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMatcher([]string{"Value"}, options{tolerance: tt.tol})
+			if got := m.equal(0, tt.a, tt.b); got != tt.want {
+				t.Fatalf("equal(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatcher_ColumnTolerance(t *testing.T) {
+	m := newMatcher([]string{"Total", "Rate"}, options{
+		tolerance:        tolerance{abs: 0.5},
+		columnTolerances: map[string]tolerance{"Rate": {abs: 0.001}},
+	})
+
+	if !m.equal(0, 10.0, 10.4) {
+		t.Fatal("expected global tolerance to apply to Total")
+	}
+	if m.equal(1, 0.10, 0.11) {
+		t.Fatal("expected column tolerance to override global tolerance for Rate")
+	}
+}
+
+func TestMatcher_EquateNaN(t *testing.T) {
+	m := newMatcher([]string{"Value"}, options{nanEqual: true})
+
+	if !m.equal(0, math.NaN(), math.NaN()) {
+		t.Fatal("expected NaN == NaN with EquateNaN")
+	}
+	if m.equal(0, math.NaN(), 1.0) {
+		t.Fatal("expected NaN != 1 with EquateNaN")
+	}
+}
+
+type level uint
+
+func (l level) String() string { return fmt.Sprintf("level %d", uint(l)) }
+
+type signal int8
+
+func TestNumericDelta(t *testing.T) {
+	type cents int64
+
+	tests := []struct {
+		name   string
+		a, b   any
+		cells  cellFormatter
+		want   string
+		wantOK bool
+	}{
+		{name: "int up", a: 25, b: 26, want: "+1", wantOK: true},
+		{name: "int down", a: 25, b: 20, want: "-5", wantOK: true},
+		{name: "named int", a: cents(100), b: cents(150), want: "+50", wantOK: true},
+		{name: "uint down", a: uint(5), b: uint(2), want: "-3", wantOK: true},
+		{name: "float", a: 0.5, b: 0.25, want: "-0.25", wantOK: true},
+		{name: "float32", a: float32(0.1), b: float32(0.2), want: "+0.1", wantOK: true},
+		{name: "nan", a: math.NaN(), b: 1.0, wantOK: false},
+		{name: "mixed types", a: 1, b: int64(2), want: "+1", wantOK: true},
+		{name: "int and float", a: 30, b: 29.5, want: "-0.5", wantOK: true},
+		{name: "int and string", a: 1, b: "2", wantOK: false},
+		{name: "string", a: "a", b: "b", wantOK: false},
+		{name: "nil", a: nil, b: 1, wantOK: false},
+		{name: "duration", a: time.Second, b: 2 * time.Second, want: "+1s", wantOK: true},
+		{name: "duration down", a: 2 * time.Second, b: 500 * time.Millisecond, want: "-1.5s", wantOK: true},
+		{name: "month", a: time.January, b: time.March, wantOK: false},
+		{name: "weekday", a: time.Monday, b: time.Wednesday, wantOK: false},
+		{name: "stringer uint", a: level(1), b: level(3), wantOK: false},
+		{name: "formatter overflow", a: signal(-100), b: signal(100), cells: cellFormatter{formatters: []typedFormatter{{
+			typ: reflect.TypeFor[signal](), format: func(v any) string { return fmt.Sprintf("%ddB", int8(v.(signal))) },
+		}}}, wantOK: false},
+		{name: "formatter", a: cents(100), b: cents(150), cells: cellFormatter{formatters: []typedFormatter{{
+			typ: reflect.TypeFor[cents](), format: func(v any) string { return fmt.Sprintf("$%.2f", float64(v.(cents))/100) },
+		}}}, want: "+$0.50", wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := numericDelta(tt.a, tt.b, tt.cells)
+			if ok != tt.wantOK || got != tt.want {
+				t.Fatalf("numericDelta(%v, %v) = %q, %v; want %q, %v", tt.a, tt.b, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+type money struct {
+	cents int64
+	label string
+}
+
+func (m money) Equal(other money) bool {
+	return m.cents == other.cents
+}
+
+type version struct {
+	major, minor int
+}
+
+func (v *version) Equal(other *version) bool {
+	return v.major == other.major
+}
+
+func TestMatcher_EqualMethod(t *testing.T) {
+	m := newMatcher([]string{"Value"}, options{})
+
+	utc := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	local := utc.In(time.FixedZone("EST", -5*60*60))
+	if !m.equal(0, utc, local) {
+		t.Fatal("expected time.Time values for the same instant to be equal via Equal")
+	}
+	if !m.equal(0, money{cents: 100, label: "a"}, money{cents: 100, label: "b"}) {
+		t.Fatal("expected value-receiver Equal method to be used")
+	}
+	if !m.equal(0, &version{1, 2}, &version{1, 3}) {
+		t.Fatal("expected pointer-receiver Equal method to be used")
+	}
+	if m.equal(0, &version{1, 2}, (*version)(nil)) {
+		t.Fatal("expected nil pointer to differ from non-nil without calling Equal")
+	}
+	if !m.equal(0, (*version)(nil), (*version)(nil)) {
+		t.Fatal("expected nil pointers to be equal")
+	}
+}
+
+func TestMatcher_EqualMethodThroughContainers(t *testing.T) {
+	m := newMatcher([]string{"Value"}, options{})
+
+	now := time.Now()
+	later := now.Add(time.Second)
+	if !m.equal(0, &now, ptr(now.Round(0))) {
+		t.Fatal("expected *time.Time values for the same instant to be equal via Equal")
+	}
+	if m.equal(0, &now, &later) {
+		t.Fatal("expected *time.Time values for different instants to differ")
+	}
+	if m.equal(0, &now, (*time.Time)(nil)) {
+		t.Fatal("expected a nil *time.Time to differ from a non-nil one")
+	}
+	if !m.equal(0, []time.Time{now, later}, []time.Time{now.Round(0), later.Round(0)}) {
+		t.Fatal("expected []time.Time values to be compared element-wise via Equal")
+	}
+	if m.equal(0, []time.Time{now}, []time.Time{now, later}) {
+		t.Fatal("expected []time.Time values of different lengths to differ")
+	}
+	if !m.equal(0, map[string]*time.Time{"at": &now}, map[string]*time.Time{"at": ptr(now.Round(0))}) {
+		t.Fatal("expected map values to be compared via Equal")
+	}
+
+	type event struct {
+		Name string
+		At   time.Time
+	}
+	if !m.equal(0, event{"a", now}, event{"a", now.Round(0)}) {
+		t.Fatal("expected struct fields to be compared via Equal")
+	}
+}
+
+func TestMatcher_ToleranceThroughContainers(t *testing.T) {
+	m := newMatcher([]string{"Value"}, options{tolerance: tolerance{abs: 0.01}, nanEqual: true})
+
+	if !m.equal(0, []float64{1, 2}, []float64{1.001, 2.001}) {
+		t.Fatal("expected slice elements within tolerance to be equal")
+	}
+	if m.equal(0, []float64{1, 2}, []float64{1, 2.5}) {
+		t.Fatal("expected slice elements outside tolerance to differ")
+	}
+	if !m.equal(0, map[string]float64{"x": math.NaN()}, map[string]float64{"x": math.NaN()}) {
+		t.Fatal("expected EquateNaN to apply to map values")
+	}
+	if !m.equal(0, map[string]any{"x": []any{math.NaN(), 1.0}}, map[string]any{"x": []any{math.NaN(), 1.005}}) {
+		t.Fatal("expected EquateNaN and tolerance to apply to nested values")
+	}
+
+	strict := newMatcher([]string{"Value"}, options{})
+	if strict.equal(0, map[string]float64{"x": math.NaN()}, map[string]float64{"x": math.NaN()}) {
+		t.Fatal("expected NaN map values to differ without EquateNaN")
+	}
+}
+
+func TestMatcher_CyclicValues(t *testing.T) {
+	m := newMatcher([]string{"Value"}, options{})
+
+	type node struct {
+		Value int
+		Next  *node
+	}
+	a := &node{Value: 1}
+	a.Next = a
+	b := &node{Value: 1}
+	b.Next = b
+	if !m.equal(0, a, b) {
+		t.Fatal("expected equal cyclic values to be equal")
+	}
+	c := &node{Value: 2}
+	c.Next = c
+	if m.equal(0, a, c) {
+		t.Fatal("expected different cyclic values to differ")
+	}
+}
+
+func TestMatcher_TypeComparer(t *testing.T) {
+	m := newMatcher([]string{"Value"}, options{comparers: []typedComparer{
+		newTypedComparer(func(a, b *big.Int) bool { return a.Cmp(b) == 0 }),
+		newTypedComparer(func(a, b money) bool { return a.label == b.label }),
+	}})
+
+	if !m.equal(0, big.NewInt(42), big.NewInt(42)) {
+		t.Fatal("expected *big.Int comparer to be used")
+	}
+	if m.equal(0, big.NewInt(42), big.NewInt(43)) {
+		t.Fatal("expected *big.Int comparer to report inequality")
+	}
+	if m.equal(0, money{cents: 100, label: "a"}, money{cents: 100, label: "b"}) {
+		t.Fatal("expected type comparer to take precedence over Equal method")
+	}
+}
+
+func TestMatcher_InterfaceComparer(t *testing.T) {
+	m := newMatcher([]string{"Value"}, options{comparers: []typedComparer{
+		newTypedComparer(func(a, b fmt.Stringer) bool { return a.String() == b.String() }),
+	}})
+
+	if !m.equal(0, net.IPv4(10, 0, 0, 1), net.ParseIP("10.0.0.1").To4()) {
+		t.Fatal("expected interface comparer to apply to implementing types")
+	}
+}
+
+func TestMatcher_ColumnComparer(t *testing.T) {
+	m := newMatcher([]string{"Name", "Email"}, options{
+		comparers: []typedComparer{newTypedComparer(func(a, b string) bool { return false })},
+		columnComparers: map[string]typedComparer{
+			"Email": newTypedComparer(strings.EqualFold),
+		},
+	})
+
+	if !m.equal(1, "Alice@Example.com", "alice@example.com") {
+		t.Fatal("expected column comparer to apply to Email")
+	}
+	if m.equal(0, "Alice", "Alice") {
+		t.Fatal("expected type comparer to apply to Name")
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestDiff_NullableTimeColumn(t *testing.T) {
+	type Row struct {
+		ID        int
+		DeletedAt *time.Time
+		Seen      []time.Time
+	}
+	now := time.Now()
+
+	result, err := Diff([]Row{{ID: 1, DeletedAt: &now, Seen: []time.Time{now}}}, []Row{{ID: 1, DeletedAt: ptr(now.Round(0)), Seen: []time.Time{now.Round(0)}}})
+	if err != nil {
+		t.Fatalf("Diff returned unexpected error: %v", err)
+	}
+	if !result.Equal() {
+		t.Fatalf("expected equal instants to match, got:\n%s", result)
+	}
+}
+
+func TestMatcher_ByValue(t *testing.T) {
+	type name string
+	m := newMatcher([]string{"Value"}, options{})
+	if m.equal(0, 30, int64(30)) || m.equal(0, "a", name("a")) {
+		t.Fatal("expected values of different types to differ by default")
+	}
+
+	m.byValue = true
+	tests := []struct {
+		name string
+		a, b any
+		want bool
+	}{
+		{name: "int and int64", a: 30, b: int64(30), want: true},
+		{name: "int and float64", a: 1, b: 1.0, want: true},
+		{name: "int and fractional float64", a: 1, b: 1.5, want: false},
+		{name: "uint and negative int", a: uint(1), b: -1, want: false},
+		{name: "named string", a: "a", b: name("a"), want: true},
+		{name: "bool", a: true, b: true, want: true},
+		{name: "string and int", a: "1", b: 1, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.equal(0, tt.a, tt.b); got != tt.want {
+				t.Fatalf("equal(%#v, %#v) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
@@ -3,71 +3,564 @@ package datadiff
 import (
 	"fmt"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 // dataset represents a normalized list of structs for comparison.
 type dataset struct {
-	typeName string   // struct type name, e.g. "Person"
-	columns  []string // exported field names in declaration order
+	typeName string       // element type name, e.g. "Person" or "*Person"
+	elemType reflect.Type // element type of the list
+	columns  []string     // column names: field names unless renamed by tags
 	rows     []row
+
+	keyColumns []string       // columns tagged `datadiff:"key"`
+	types      []reflect.Type // static column types, parallel to columns
+
+	// maps is set when rows are maps. Columns are then the sorted keys
+	// of all rows, and absent keys hold [MissingKey] values.
+	maps bool
+}
+
+// MissingKey is the value reported in [RowDiff] for a column whose key is
+// absent from a map row. It is shown as "<missing>", distinct from a nil
+// or zero value stored under the key.
+type MissingKey struct{}
+
+// String returns "<missing>".
+func (MissingKey) String() string {
+	return "<missing>"
 }
 
 // row holds the field values for a single struct element.
 type row struct {
 	values []any // one value per column, same order as dataset.columns
+	isNil  bool  // element is a nil pointer; values are all nil
+
+	// elem is the original element, kept only for options that work on
+	// whole elements, such as a key function or row comparer.
+	elem any
 }
 
-// extract validates that v is a slice of structs and returns a dataset.
+// extract validates that v is a list of structs, pointers to structs, or
+// maps with string keys, and returns a dataset. Nil pointer and nil map
+// elements become nil rows. Lists are slices, arrays, iter.Seq values and
+// channels (see [elements]). Map rows are handled by [extractMaps].
+//
+// For structs, columns are the exported fields, adjusted by `datadiff` struct tags
+// (see [fieldTag]). Fields of embedded structs are promoted to columns of
+// their own, and nested struct fields are flattened into dotted columns
+// such as "Address.Zip" (see [structFields]).
 //
 // Errors:
 //   - v is nil
-//   - v is not a slice
-//   - slice element type is not a struct (pointers to structs are not accepted)
-//   - struct has zero exported fields
-func extract(v any) (dataset, error) {
+//   - v is not a slice, array, iter.Seq or receivable channel
+//   - element type is not a struct, a pointer to a struct or a map with
+//     string keys
+//   - struct has zero exported fields, or all are skipped by tags
+//   - a field has an invalid `datadiff` tag
+//   - two columns share a name
+func extract(v any, opts options) (dataset, error) {
 	if v == nil {
 		return dataset{}, fmt.Errorf("datadiff: input is nil")
 	}
 
-	value := reflect.ValueOf(v)
-	if value.Kind() != reflect.Slice {
-		return dataset{}, fmt.Errorf("datadiff: expected slice, got %T", v)
+	elemType, elems, container, err := elements(reflect.ValueOf(v))
+	if err != nil {
+		return dataset{}, err
 	}
 
-	elemType := reflect.TypeOf(v).Elem()
+	if elemType.Kind() == reflect.Map {
+		return extractMaps(elemType, elems, opts.needsElements())
+	}
+
+	listElemType := elemType
+	typeName := elemType.Name()
+	pointers := elemType.Kind() == reflect.Pointer && elemType.Elem().Kind() == reflect.Struct
+	if pointers {
+		elemType = elemType.Elem()
+		typeName = "*" + elemType.Name()
+	}
 	if elemType.Kind() != reflect.Struct {
-		return dataset{}, fmt.Errorf("datadiff: expected slice of structs, got slice of %s", elemType.Kind())
+		return dataset{}, fmt.Errorf("datadiff: expected slice of structs, got %s of %s", container, elemType.Kind())
 	}
 
-	columns := make([]string, 0, elemType.NumField())
-	exportedFieldIndexes := make([]int, 0, elemType.NumField())
-	for i := 0; i < elemType.NumField(); i++ {
-		field := elemType.Field(i)
-		if !field.IsExported() {
-			continue
+	if !hasExportedFields(elemType) {
+		return dataset{}, fmt.Errorf("datadiff: struct %s has no exported fields", elemType.Name())
+	}
+
+	fields, err := structFields(elemType, nil, "", 0, opts)
+	if err != nil {
+		return dataset{}, err
+	}
+	if len(fields) == 0 {
+		return dataset{}, fmt.Errorf("datadiff: struct %s has no columns: all exported fields are skipped by tags", elemType.Name())
+	}
+
+	columns := make([]string, len(fields))
+	types := make([]reflect.Type, len(fields))
+	var keyColumns []string
+	for i, field := range fields {
+		if columnIndex(columns[:i], field.name) >= 0 {
+			return dataset{}, fmt.Errorf("datadiff: struct %s has duplicate column name %q", elemType.Name(), field.name)
+		}
+		columns[i] = field.name
+		types[i] = field.typ
+		if field.tag.key {
+			keyColumns = append(keyColumns, field.name)
 		}
-		exportedFieldIndexes = append(exportedFieldIndexes, i)
-		columns = append(columns, field.Name)
 	}
 
-	if len(columns) == 0 {
-		return dataset{}, fmt.Errorf("datadiff: struct %s has no exported fields", elemType.Name())
+	result := dataset{
+		typeName:   typeName,
+		elemType:   listElemType,
+		columns:    columns,
+		rows:       make([]row, len(elems)),
+		keyColumns: keyColumns,
+		types:      types,
+	}
+
+	keep := opts.needsElements()
+	for i, element := range elems {
+		values := make([]any, len(fields))
+		var elem any
+		if keep {
+			elem = element.Interface()
+		}
+		if pointers {
+			if element.IsNil() {
+				result.rows[i] = row{values: values, isNil: true, elem: elem}
+				continue
+			}
+			element = element.Elem()
+		}
+		for j, field := range fields {
+			values[j] = fieldValue(element, field.index)
+		}
+		result.rows[i] = row{values: values, elem: elem}
+	}
+
+	return result, nil
+}
+
+// extractMaps builds a dataset from map rows. Columns are the sorted
+// union of the keys of all rows; see [dataset.align] for combining the
+// columns of two lists. If keep is set, rows keep their elements.
+func extractMaps(mapType reflect.Type, elems []reflect.Value, keep bool) (dataset, error) {
+	if mapType.Key().Kind() != reflect.String {
+		return dataset{}, fmt.Errorf("datadiff: expected map with string keys, got %s", mapType)
+	}
+
+	typeName := mapType.Name()
+	if typeName == "" {
+		typeName = strings.ReplaceAll(mapType.String(), "interface {}", "any")
 	}
 
+	seen := make(map[string]bool)
+	var columns []string
+	for _, element := range elems {
+		keys := element.MapRange()
+		for keys.Next() {
+			if key := keys.Key().String(); !seen[key] {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+		}
+	}
+	sort.Strings(columns)
+
 	result := dataset{
-		typeName: elemType.Name(),
+		typeName: typeName,
+		elemType: mapType,
 		columns:  columns,
-		rows:     make([]row, value.Len()),
+		rows:     make([]row, len(elems)),
+		maps:     true,
+	}
+	// Interface-typed values have no useful static type; leave types unset
+	// so that column options are checked against the values instead.
+	if valueType := mapType.Elem(); valueType.Kind() != reflect.Interface {
+		result.types = make([]reflect.Type, len(columns))
+		for i := range columns {
+			result.types[i] = valueType
+		}
 	}
 
-	for i := 0; i < value.Len(); i++ {
-		element := value.Index(i)
-		values := make([]any, len(exportedFieldIndexes))
-		for j, fieldIndex := range exportedFieldIndexes {
-			values[j] = element.Field(fieldIndex).Interface()
+	for i, element := range elems {
+		values := make([]any, len(columns))
+		var elem any
+		if keep {
+			elem = element.Interface()
+		}
+		if element.IsNil() {
+			result.rows[i] = row{values: values, isNil: true, elem: elem}
+			continue
 		}
-		result.rows[i] = row{values: values}
+		for j, column := range columns {
+			value := element.MapIndex(reflect.ValueOf(column).Convert(mapType.Key()))
+			if !value.IsValid() {
+				values[j] = MissingKey{}
+				continue
+			}
+			values[j] = value.Interface()
+		}
+		result.rows[i] = row{values: values, elem: elem}
 	}
 
 	return result, nil
 }
+
+// elements returns the element type and the elements of v in order, with
+// the kind of container v is for error messages. v may be a slice, an
+// array, an iter.Seq, which is run to completion, or a channel that can be
+// received from, which is drained until closed.
+func elements(v reflect.Value) (reflect.Type, []reflect.Value, string, error) {
+	t := v.Type()
+
+	switch {
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		elems := make([]reflect.Value, v.Len())
+		for i := range elems {
+			elems[i] = v.Index(i)
+		}
+		return t.Elem(), elems, t.Kind().String(), nil
+
+	case t.Kind() == reflect.Chan && t.ChanDir()&reflect.RecvDir != 0:
+		if v.IsNil() {
+			return nil, nil, "", fmt.Errorf("datadiff: input is a nil channel")
+		}
+		var elems []reflect.Value
+		for {
+			elem, ok := v.Recv()
+			if !ok {
+				return t.Elem(), elems, "channel", nil
+			}
+			elems = append(elems, elem)
+		}
+
+	case isSeq(t):
+		if v.IsNil() {
+			return nil, nil, "", fmt.Errorf("datadiff: input is a nil iter.Seq")
+		}
+		var elems []reflect.Value
+		yield := reflect.MakeFunc(t.In(0), func(args []reflect.Value) []reflect.Value {
+			elems = append(elems, args[0])
+			return []reflect.Value{reflect.ValueOf(true)}
+		})
+		v.Call([]reflect.Value{yield})
+		return t.In(0).In(0), elems, "iter.Seq", nil
+	}
+
+	return nil, nil, "", fmt.Errorf("datadiff: expected slice, array, iter.Seq or channel, got %s", t)
+}
+
+// isSeq reports whether t has the shape of iter.Seq[V]:
+// func(yield func(V) bool).
+func isSeq(t reflect.Type) bool {
+	if t.Kind() != reflect.Func || t.NumIn() != 1 || t.NumOut() != 0 || t.IsVariadic() {
+		return false
+	}
+	yield := t.In(0)
+	return yield.Kind() == reflect.Func && yield.NumIn() == 1 && yield.NumOut() == 1 &&
+		!yield.IsVariadic() && yield.Out(0).Kind() == reflect.Bool
+}
+
+// fieldValue returns the field of v at index. Fields promoted through a
+// nil embedded pointer have no value and are returned as nil.
+func fieldValue(v reflect.Value, index []int) any {
+	field, err := v.FieldByIndexErr(index)
+	if err != nil {
+		return nil
+	}
+	return field.Interface()
+}
+
+// structFields returns the columns of struct type t in display order.
+// index and prefix locate t within the row's element type; depth is its
+// nesting level. Nested structs are expanded in place of their field.
+//
+// Fields of embedded structs are promoted as Go promotes them: they become
+// columns of t itself, shallower fields shadow deeper ones, and names that
+// are ambiguous at the same depth are dropped.
+func structFields(t reflect.Type, index []int, prefix string, depth int, opts options) ([]structField, error) {
+	// promoted holds the index paths of embedded fields whose fields are
+	// promoted, mapped to their tag; fields under any other embedded field
+	// are hidden.
+	promoted := map[string]fieldTag{indexKey(nil): {}}
+
+	var fields []structField
+	for _, field := range reflect.VisibleFields(t) {
+		parent, ok := promoted[indexKey(field.Index[:len(field.Index)-1])]
+		if !ok {
+			continue
+		}
+
+		tag, err := parseTag(field.Tag.Get("datadiff"))
+		if err != nil {
+			return nil, fmt.Errorf("datadiff: field %s.%s: %w", t.Name(), field.Name, err)
+		}
+		tag.key = tag.key || parent.key
+		if tag.skip {
+			continue
+		}
+
+		if field.Anonymous && tag.name == "" && promotable(field.Type, opts) {
+			promoted[indexKey(field.Index)] = tag
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+		if tag.name != "" {
+			name = tag.name
+		}
+
+		fields = append(fields, structField{
+			name:  prefix + name,
+			index: append(append([]int(nil), index...), field.Index...),
+			typ:   field.Type,
+			tag:   tag,
+		})
+	}
+
+	// Fields with an order hint come first, by ascending hint; the rest
+	// keep their declaration order.
+	sort.SliceStable(fields, func(i, j int) bool {
+		if fields[i].tag.hasOrder != fields[j].tag.hasOrder {
+			return fields[i].tag.hasOrder
+		}
+		return fields[i].tag.order < fields[j].tag.order
+	})
+
+	expanded := make([]structField, 0, len(fields))
+	for _, field := range fields {
+		if !flattenable(field.typ, depth, opts) {
+			expanded = append(expanded, field)
+			continue
+		}
+
+		nested, err := structFields(field.typ, field.index, field.name+".", depth+1, opts)
+		if err != nil {
+			return nil, err
+		}
+		if field.tag.key {
+			for i := range nested {
+				nested[i].tag.key = true
+			}
+		}
+		expanded = append(expanded, nested...)
+	}
+
+	return expanded, nil
+}
+
+// indexKey encodes a field index path as a map key.
+func indexKey(index []int) string {
+	return fmt.Sprint(index)
+}
+
+// promotable reports whether the fields of an embedded field of type t
+// are promoted into columns. Embedded structs and pointers to structs are
+// promoted unless they behave as values (see [flattenable]); other
+// embedded types are ordinary columns named after their type.
+func promotable(t reflect.Type, opts options) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && hasExportedFields(t) && !valueLike(t, opts)
+}
+
+// flattenable reports whether a field of type t at the given nesting depth
+// is expanded into one column per nested field. Structs are flattened
+// unless the depth limit is reached, they have no exported fields, or they
+// behave as values: types with a String or Equal method (such as
+// time.Time) or a registered comparer stay single columns.
+func flattenable(t reflect.Type, depth int, opts options) bool {
+	if t.Kind() != reflect.Struct || !hasExportedFields(t) {
+		return false
+	}
+	if opts.maxDepthSet && depth >= opts.maxDepth {
+		return false
+	}
+	return !valueLike(t, opts)
+}
+
+// valueLike reports whether values of t should be compared and shown as a
+// whole rather than field by field.
+func valueLike(t reflect.Type, opts options) bool {
+	if t.Implements(stringerType) || equalMethod(t) != nil {
+		return true
+	}
+	for _, comparer := range opts.comparers {
+		if comparer.typ == t {
+			return true
+		}
+	}
+	return false
+}
+
+var stringerType = reflect.TypeFor[fmt.Stringer]()
+
+// hasExportedFields reports whether struct type t has an exported field,
+// including fields promoted from embedded structs.
+func hasExportedFields(t reflect.Type) bool {
+	for _, field := range reflect.VisibleFields(t) {
+		if field.IsExported() {
+			return true
+		}
+	}
+	return false
+}
+
+// project returns a copy of ds restricted to the columns at indexes, in
+// that order.
+func (ds dataset) project(indexes []int) dataset {
+	projected := dataset{
+		typeName:   ds.typeName,
+		elemType:   ds.elemType,
+		columns:    make([]string, len(indexes)),
+		rows:       make([]row, len(ds.rows)),
+		keyColumns: ds.keyColumns,
+		maps:       ds.maps,
+	}
+
+	for i, index := range indexes {
+		projected.columns[i] = ds.columns[index]
+		if index < len(ds.types) {
+			projected.types = append(projected.types, ds.types[index])
+		}
+	}
+
+	for r := range ds.rows {
+		values := make([]any, len(indexes))
+		for i, index := range indexes {
+			values[i] = ds.rows[r].values[index]
+		}
+		projected.rows[r] = ds.rows[r]
+		projected.rows[r].values = values
+	}
+
+	return projected
+}
+
+// align returns ds with the given columns, which must include all of its
+// own. Columns ds does not have hold [MissingKey] values. It is used to
+// give two lists of map rows the same columns.
+func (ds dataset) align(columns []string) dataset {
+	aligned := dataset{
+		typeName:   ds.typeName,
+		elemType:   ds.elemType,
+		columns:    columns,
+		rows:       make([]row, len(ds.rows)),
+		keyColumns: ds.keyColumns,
+		maps:       ds.maps,
+	}
+	if len(ds.types) > 0 {
+		aligned.types = make([]reflect.Type, len(columns))
+		for i := range aligned.types {
+			aligned.types[i] = ds.types[0]
+		}
+	}
+
+	indexes := make([]int, len(columns))
+	for i, column := range columns {
+		indexes[i] = columnIndex(ds.columns, column)
+	}
+
+	for r, source := range ds.rows {
+		values := make([]any, len(columns))
+		for i, index := range indexes {
+			switch {
+			case source.isNil:
+			case index < 0:
+				values[i] = MissingKey{}
+			default:
+				values[i] = source.values[index]
+			}
+		}
+		aligned.rows[r] = source
+		aligned.rows[r].values = values
+	}
+
+	return aligned
+}
+
+// structField is a struct field selected as a column.
+type structField struct {
+	name  string       // column name, dotted for nested fields
+	index []int        // field index path from the row's element type
+	typ   reflect.Type // field type
+	tag   fieldTag
+}
+
+// fieldTag holds the parsed options of a `datadiff` struct tag.
+//
+// Supported options, comma-separated:
+//   - "-": skip the field (must be the whole tag)
+//   - "key": the field is part of the row key
+//   - "name=<column>": use <column> as the column name
+//   - "order=<n>": place the column before fields without a hint,
+//     sorted by ascending n
+type fieldTag struct {
+	skip     bool
+	key      bool
+	name     string
+	order    int
+	hasOrder bool
+}
+
+// parseTag parses a comma-separated `datadiff` struct tag value.
+func parseTag(tag string) (fieldTag, error) {
+	var result fieldTag
+	if tag == "" {
+		return result, nil
+	}
+	if tag == "-" {
+		result.skip = true
+		return result, nil
+	}
+
+	for _, option := range strings.Split(tag, ",") {
+		option = strings.TrimSpace(option)
+		name, value, hasValue := strings.Cut(option, "=")
+
+		switch {
+		case option == "key":
+			result.key = true
+		case hasValue && name == "name":
+			if value == "" {
+				return fieldTag{}, fmt.Errorf("empty column name in datadiff tag")
+			}
+			result.name = value
+		case hasValue && name == "order":
+			order, err := strconv.Atoi(value)
+			if err != nil {
+				return fieldTag{}, fmt.Errorf("invalid order %q in datadiff tag", value)
+			}
+			result.order = order
+			result.hasOrder = true
+		default:
+			return fieldTag{}, fmt.Errorf("unknown datadiff tag option %q", option)
+		}
+	}
+
+	return result, nil
+}
+
+// unionColumns returns the sorted union of two sorted column lists.
+func unionColumns(a, b []string) []string {
+	union := make([]string, 0, len(a)+len(b))
+	for len(a) > 0 || len(b) > 0 {
+		switch {
+		case len(b) == 0 || len(a) > 0 && a[0] < b[0]:
+			union, a = append(union, a[0]), a[1:]
+		case len(a) == 0 || b[0] < a[0]:
+			union, b = append(union, b[0]), b[1:]
+		default:
+			union, a, b = append(union, a[0]), a[1:], b[1:]
+		}
+	}
+	return union
+}
@@ -1,7 +1,9 @@
 package datadiff
 
 import (
+	"iter"
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -19,7 +21,7 @@ func TestExtract_ValidSlice(t *testing.T) {
 		{Name: "Charlie", Age: 35},
 	}
 
-	got, err := extract(input)
+	got, err := extract(input, options{})
 	if err != nil {
 		t.Fatalf("extract returned unexpected error: %v", err)
 	}
@@ -56,7 +58,7 @@ func TestExtract_EmptySlice(t *testing.T) {
 	}
 
 	var input []Person
-	got, err := extract(input)
+	got, err := extract(input, options{})
 	if err != nil {
 		t.Fatalf("extract returned unexpected error: %v", err)
 	}
@@ -76,7 +78,7 @@ func TestExtract_EmptySlice(t *testing.T) {
 }
 
 func TestExtract_NilInput(t *testing.T) {
-	_, err := extract(nil)
+	_, err := extract(nil, options{})
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -94,11 +96,13 @@ func TestExtract_NotASlice(t *testing.T) {
 		{name: "string", input: "hello"},
 		{name: "int", input: 42},
 		{name: "struct", input: struct{ X int }{X: 1}},
+		{name: "send-only channel", input: make(chan<- struct{ X int })},
+		{name: "func", input: func(int) {}},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := extract(tt.input)
+			_, err := extract(tt.input, options{})
 			if err == nil {
 				t.Fatal("expected error, got nil")
 			}
@@ -121,12 +125,13 @@ func TestExtract_SliceOfNonStruct(t *testing.T) {
 	}{
 		{name: "slice of ints", input: []int{1, 2}},
 		{name: "slice of strings", input: []string{"a", "b"}},
-		{name: "slice of pointers", input: []*Person{{Name: "Alice"}}},
+		{name: "slice of pointers to ints", input: []*int{nil}},
+		{name: "slice of double pointers", input: []**Person{nil}},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := extract(tt.input)
+			_, err := extract(tt.input, options{})
 			if err == nil {
 				t.Fatal("expected error, got nil")
 			}
@@ -146,7 +151,7 @@ func TestExtract_UnexportedFieldsSkipped(t *testing.T) {
 	}
 
 	input := []Person{{Name: "Alice", age: 99, City: "NYC"}}
-	got, err := extract(input)
+	got, err := extract(input, options{})
 	if err != nil {
 		t.Fatalf("extract returned unexpected error: %v", err)
 	}
@@ -168,7 +173,7 @@ func TestExtract_NoExportedFields(t *testing.T) {
 		age  int
 	}
 
-	_, err := extract([]hidden{{name: "alice", age: 30}})
+	_, err := extract([]hidden{{name: "alice", age: 30}}, options{})
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -186,7 +191,7 @@ func TestExtract_AnonymousStruct(t *testing.T) {
 		{X: 20},
 	}
 
-	got, err := extract(input)
+	got, err := extract(input, options{})
 	if err != nil {
 		t.Fatalf("extract returned unexpected error: %v", err)
 	}
@@ -226,7 +231,7 @@ func TestExtract_VariousFieldTypes(t *testing.T) {
 		CreatedAt: now,
 	}}
 
-	got, err := extract(input)
+	got, err := extract(input, options{})
 	if err != nil {
 		t.Fatalf("extract returned unexpected error: %v", err)
 	}
@@ -241,3 +246,517 @@ func TestExtract_VariousFieldTypes(t *testing.T) {
 		t.Fatalf("row mismatch: got %#v, want %#v", got.rows[0].values, wantValues)
 	}
 }
+
+func TestExtract_KeyTag(t *testing.T) {
+	type Account struct {
+		Tenant string `datadiff:"key"`
+		ID     int    `datadiff:"key"`
+		Name   string
+	}
+
+	got, err := extract([]Account{{Tenant: "acme", ID: 1, Name: "Alice"}}, options{})
+	if err != nil {
+		t.Fatalf("extract returned unexpected error: %v", err)
+	}
+
+	wantKeys := []string{"Tenant", "ID"}
+	if !reflect.DeepEqual(got.keyColumns, wantKeys) {
+		t.Fatalf("key columns mismatch: got %#v, want %#v", got.keyColumns, wantKeys)
+	}
+}
+
+func TestExtract_InvalidTag(t *testing.T) {
+	type Account struct {
+		ID int `datadiff:"primary"`
+	}
+
+	_, err := extract([]Account{}, options{})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), `field Account.ID: unknown datadiff tag option "primary"`) {
+		t.Fatalf("error mismatch: got %q", err.Error())
+	}
+}
+
+func TestExtract_TagSkipAndRename(t *testing.T) {
+	type Account struct {
+		ID        int    `datadiff:"name=user_id"`
+		Name      string `datadiff:"name=full_name"`
+		Password  string `datadiff:"-"`
+		CreatedAt string
+	}
+
+	got, err := extract([]Account{{ID: 1, Name: "Alice", Password: "secret", CreatedAt: "today"}}, options{})
+	if err != nil {
+		t.Fatalf("extract returned unexpected error: %v", err)
+	}
+
+	wantColumns := []string{"user_id", "full_name", "CreatedAt"}
+	if !reflect.DeepEqual(got.columns, wantColumns) {
+		t.Fatalf("columns mismatch: got %#v, want %#v", got.columns, wantColumns)
+	}
+
+	wantValues := []any{1, "Alice", "today"}
+	if !reflect.DeepEqual(got.rows[0].values, wantValues) {
+		t.Fatalf("row mismatch: got %#v, want %#v", got.rows[0].values, wantValues)
+	}
+}
+
+func TestExtract_TagOrder(t *testing.T) {
+	type Order struct {
+		Notes    string
+		Customer string `datadiff:"order=2"`
+		Total    int    `datadiff:"order=1"`
+		Region   string
+		ID       int `datadiff:"key,order=0,name=order_id"`
+	}
+
+	got, err := extract([]Order{{Notes: "n", Customer: "c", Total: 9, Region: "r", ID: 7}}, options{})
+	if err != nil {
+		t.Fatalf("extract returned unexpected error: %v", err)
+	}
+
+	wantColumns := []string{"order_id", "Total", "Customer", "Notes", "Region"}
+	if !reflect.DeepEqual(got.columns, wantColumns) {
+		t.Fatalf("columns mismatch: got %#v, want %#v", got.columns, wantColumns)
+	}
+
+	wantValues := []any{7, 9, "c", "n", "r"}
+	if !reflect.DeepEqual(got.rows[0].values, wantValues) {
+		t.Fatalf("row mismatch: got %#v, want %#v", got.rows[0].values, wantValues)
+	}
+
+	if !reflect.DeepEqual(got.keyColumns, []string{"order_id"}) {
+		t.Fatalf("key columns mismatch: got %#v", got.keyColumns)
+	}
+}
+
+func TestExtract_TagErrors(t *testing.T) {
+	type allSkipped struct {
+		A int `datadiff:"-"`
+	}
+	type duplicate struct {
+		A int `datadiff:"name=X"`
+		X int
+	}
+	type badOrder struct {
+		A int `datadiff:"order=first"`
+	}
+	type emptyName struct {
+		A int `datadiff:"name="`
+	}
+
+	tests := []struct {
+		name    string
+		input   any
+		wantErr string
+	}{
+		{name: "all skipped", input: []allSkipped{}, wantErr: "all exported fields are skipped by tags"},
+		{name: "duplicate name", input: []duplicate{}, wantErr: `duplicate column name "X"`},
+		{name: "bad order", input: []badOrder{}, wantErr: `invalid order "first"`},
+		{name: "empty name", input: []emptyName{}, wantErr: "empty column name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := extract(tt.input, options{})
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error mismatch: got %q, want substring %q", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+type geo struct {
+	Lat, Lng float64
+}
+
+type address struct {
+	Street string
+	Zip    string `datadiff:"name=ZIP"`
+	Notes  string `datadiff:"-"`
+	Geo    geo
+}
+
+type customer struct {
+	Name    string
+	Address address
+	Since   time.Time
+}
+
+func TestExtract_FlattensNestedStructs(t *testing.T) {
+	since := time.Date(2020, time.May, 1, 0, 0, 0, 0, time.UTC)
+	input := []customer{{
+		Name:    "Alice",
+		Address: address{Street: "1 Main St", Zip: "10001", Notes: "x", Geo: geo{Lat: 1.5, Lng: 2.5}},
+		Since:   since,
+	}}
+
+	got, err := extract(input, options{})
+	if err != nil {
+		t.Fatalf("extract returned unexpected error: %v", err)
+	}
+
+	wantColumns := []string{"Name", "Address.Street", "Address.ZIP", "Address.Geo.Lat", "Address.Geo.Lng", "Since"}
+	if !reflect.DeepEqual(got.columns, wantColumns) {
+		t.Fatalf("columns mismatch: got %#v, want %#v", got.columns, wantColumns)
+	}
+
+	wantValues := []any{"Alice", "1 Main St", "10001", 1.5, 2.5, since}
+	if !reflect.DeepEqual(got.rows[0].values, wantValues) {
+		t.Fatalf("row mismatch: got %#v, want %#v", got.rows[0].values, wantValues)
+	}
+
+	if got.types[3] != reflect.TypeFor[float64]() {
+		t.Fatalf("nested column type mismatch: got %v", got.types[3])
+	}
+}
+
+func TestExtract_MaxDepth(t *testing.T) {
+	input := []customer{{Name: "Alice", Address: address{Street: "1 Main St", Geo: geo{Lat: 1}}}}
+
+	tests := []struct {
+		name        string
+		opts        options
+		wantColumns []string
+	}{
+		{name: "zero", opts: options{maxDepthSet: true}, wantColumns: []string{"Name", "Address", "Since"}},
+		{name: "one", opts: options{maxDepth: 1, maxDepthSet: true}, wantColumns: []string{"Name", "Address.Street", "Address.ZIP", "Address.Geo", "Since"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extract(input, tt.opts)
+			if err != nil {
+				t.Fatalf("extract returned unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got.columns, tt.wantColumns) {
+				t.Fatalf("columns mismatch: got %#v, want %#v", got.columns, tt.wantColumns)
+			}
+		})
+	}
+}
+
+func TestExtract_NestedStructWithComparerStaysWhole(t *testing.T) {
+	opts := options{comparers: []typedComparer{newTypedComparer(func(a, b geo) bool { return true })}}
+
+	got, err := extract([]address{{Street: "1 Main St"}}, opts)
+	if err != nil {
+		t.Fatalf("extract returned unexpected error: %v", err)
+	}
+
+	wantColumns := []string{"Street", "ZIP", "Geo"}
+	if !reflect.DeepEqual(got.columns, wantColumns) {
+		t.Fatalf("columns mismatch: got %#v, want %#v", got.columns, wantColumns)
+	}
+}
+
+func TestExtract_NestedKeyAndOrder(t *testing.T) {
+	type id struct {
+		Tenant string
+		Number int
+	}
+	type record struct {
+		Name string
+		ID   id `datadiff:"key,order=1"`
+	}
+
+	got, err := extract([]record{{Name: "a", ID: id{Tenant: "t", Number: 1}}}, options{})
+	if err != nil {
+		t.Fatalf("extract returned unexpected error: %v", err)
+	}
+
+	wantColumns := []string{"ID.Tenant", "ID.Number", "Name"}
+	if !reflect.DeepEqual(got.columns, wantColumns) {
+		t.Fatalf("columns mismatch: got %#v, want %#v", got.columns, wantColumns)
+	}
+	if !reflect.DeepEqual(got.keyColumns, []string{"ID.Tenant", "ID.Number"}) {
+		t.Fatalf("key columns mismatch: got %#v", got.keyColumns)
+	}
+}
+
+type auditInfo struct {
+	CreatedBy string
+	Version   int
+}
+
+type baseModel struct {
+	ID int
+	auditInfo
+}
+
+type namedEntity struct {
+	Name    string
+	Version string
+}
+
+func TestExtract_EmbeddedPromotion(t *testing.T) {
+	type product struct {
+		baseModel
+		Name  string
+		Price float64
+	}
+
+	input := []product{{baseModel: baseModel{ID: 1, auditInfo: auditInfo{CreatedBy: "alice", Version: 3}}, Name: "pen", Price: 1.5}}
+	got, err := extract(input, options{})
+	if err != nil {
+		t.Fatalf("extract returned unexpected error: %v", err)
+	}
+
+	wantColumns := []string{"ID", "CreatedBy", "Version", "Name", "Price"}
+	if !reflect.DeepEqual(got.columns, wantColumns) {
+		t.Fatalf("columns mismatch: got %#v, want %#v", got.columns, wantColumns)
+	}
+
+	wantValues := []any{1, "alice", 3, "pen", 1.5}
+	if !reflect.DeepEqual(got.rows[0].values, wantValues) {
+		t.Fatalf("row mismatch: got %#v, want %#v", got.rows[0].values, wantValues)
+	}
+}
+
+func TestExtract_EmbeddedShadowing(t *testing.T) {
+	type record struct {
+		baseModel
+		Version string // shadows baseModel.auditInfo.Version
+	}
+
+	got, err := extract([]record{{baseModel: baseModel{ID: 1, auditInfo: auditInfo{Version: 3}}, Version: "v2"}}, options{})
+	if err != nil {
+		t.Fatalf("extract returned unexpected error: %v", err)
+	}
+
+	wantColumns := []string{"ID", "CreatedBy", "Version"}
+	if !reflect.DeepEqual(got.columns, wantColumns) {
+		t.Fatalf("columns mismatch: got %#v, want %#v", got.columns, wantColumns)
+	}
+
+	wantValues := []any{1, "", "v2"}
+	if !reflect.DeepEqual(got.rows[0].values, wantValues) {
+		t.Fatalf("row mismatch: got %#v, want %#v", got.rows[0].values, wantValues)
+	}
+}
+
+func TestExtract_EmbeddedAmbiguousFieldsDropped(t *testing.T) {
+	type record struct {
+		auditInfo
+		namedEntity
+	}
+
+	got, err := extract([]record{{}}, options{})
+	if err != nil {
+		t.Fatalf("extract returned unexpected error: %v", err)
+	}
+
+	// Version is declared by both embedded structs at the same depth, so
+	// Go does not promote it.
+	wantColumns := []string{"CreatedBy", "Name"}
+	if !reflect.DeepEqual(got.columns, wantColumns) {
+		t.Fatalf("columns mismatch: got %#v, want %#v", got.columns, wantColumns)
+	}
+}
+
+func TestExtract_EmbeddedPointer(t *testing.T) {
+	type record struct {
+		*auditInfo
+		Name string
+	}
+
+	input := []record{
+		{auditInfo: &auditInfo{CreatedBy: "alice", Version: 1}, Name: "a"},
+		{Name: "b"},
+	}
+	got, err := extract(input, options{})
+	if err != nil {
+		t.Fatalf("extract returned unexpected error: %v", err)
+	}
+
+	wantColumns := []string{"CreatedBy", "Version", "Name"}
+	if !reflect.DeepEqual(got.columns, wantColumns) {
+		t.Fatalf("columns mismatch: got %#v, want %#v", got.columns, wantColumns)
+	}
+
+	wantRows := [][]any{{"alice", 1, "a"}, {nil, nil, "b"}}
+	for i := range wantRows {
+		if !reflect.DeepEqual(got.rows[i].values, wantRows[i]) {
+			t.Fatalf("row %d mismatch: got %#v, want %#v", i, got.rows[i].values, wantRows[i])
+		}
+	}
+}
+
+func TestExtract_EmbeddedTagsAndValueTypes(t *testing.T) {
+	type Stamp struct {
+		time.Time
+	}
+	type Label struct {
+		Tenant, Slug string
+	}
+	type record struct {
+		auditInfo `datadiff:"-"`
+		Label     `datadiff:"key"`
+		Stamp
+	}
+
+	got, err := extract([]record{{}}, options{})
+	if err != nil {
+		t.Fatalf("extract returned unexpected error: %v", err)
+	}
+
+	// Stamp promotes time.Time's String method, so it stays one column.
+	wantColumns := []string{"Tenant", "Slug", "Stamp"}
+	if !reflect.DeepEqual(got.columns, wantColumns) {
+		t.Fatalf("columns mismatch: got %#v, want %#v", got.columns, wantColumns)
+	}
+	if !reflect.DeepEqual(got.keyColumns, []string{"Tenant", "Slug"}) {
+		t.Fatalf("key columns mismatch: got %#v", got.keyColumns)
+	}
+}
+
+func TestExtract_SliceOfPointers(t *testing.T) {
+	type Person struct {
+		Name string
+		Age  int
+	}
+
+	got, err := extract([]*Person{{Name: "Alice", Age: 30}, nil}, options{})
+	if err != nil {
+		t.Fatalf("extract returned unexpected error: %v", err)
+	}
+
+	if got.typeName != "*Person" {
+		t.Fatalf("typeName mismatch: got %q, want %q", got.typeName, "*Person")
+	}
+	if !reflect.DeepEqual(got.rows[0], row{values: []any{"Alice", 30}}) {
+		t.Fatalf("row 0 mismatch: got %#v", got.rows[0])
+	}
+	if !reflect.DeepEqual(got.rows[1], row{values: []any{nil, nil}, isNil: true}) {
+		t.Fatalf("row 1 mismatch: got %#v", got.rows[1])
+	}
+}
+
+func TestExtract_ArraysIteratorsAndChannels(t *testing.T) {
+	type Person struct {
+		Name string
+	}
+	people := []Person{{Name: "Alice"}, {Name: "Bob"}}
+
+	ch := make(chan Person, len(people))
+	for _, p := range people {
+		ch <- p
+	}
+	close(ch)
+
+	tests := []struct {
+		name  string
+		input any
+	}{
+		{name: "array", input: [2]Person{people[0], people[1]}},
+		{name: "iter.Seq", input: slices.Values(people)},
+		{name: "receive-only channel", input: (<-chan Person)(ch)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extract(tt.input, options{})
+			if err != nil {
+				t.Fatalf("extract returned unexpected error: %v", err)
+			}
+
+			if got.typeName != "Person" {
+				t.Fatalf("typeName mismatch: got %q, want %q", got.typeName, "Person")
+			}
+			wantRows := []row{{values: []any{"Alice"}}, {values: []any{"Bob"}}}
+			if !reflect.DeepEqual(got.rows, wantRows) {
+				t.Fatalf("rows mismatch: got %#v, want %#v", got.rows, wantRows)
+			}
+		})
+	}
+}
+
+func TestExtract_IteratorOfPointers(t *testing.T) {
+	type Person struct {
+		Name string
+	}
+
+	var seq iter.Seq[*Person] = func(yield func(*Person) bool) {
+		_ = yield(&Person{Name: "Alice"}) && yield(nil)
+	}
+
+	got, err := extract(seq, options{})
+	if err != nil {
+		t.Fatalf("extract returned unexpected error: %v", err)
+	}
+	if got.typeName != "*Person" || len(got.rows) != 2 || !got.rows[1].isNil {
+		t.Fatalf("unexpected dataset: %+v", got)
+	}
+
+	_, err = extract((iter.Seq[Person])(nil), options{})
+	if err == nil || !strings.Contains(err.Error(), "nil iter.Seq") {
+		t.Fatalf("expected nil iter.Seq error, got %v", err)
+	}
+}
+
+func TestExtract_SliceOfMaps(t *testing.T) {
+	input := []map[string]any{
+		{"name": "Alice", "age": 30},
+		{"name": "Bob", "email": nil},
+		nil,
+	}
+
+	got, err := extract(input, options{})
+	if err != nil {
+		t.Fatalf("extract returned unexpected error: %v", err)
+	}
+
+	if got.typeName != "map[string]any" || !got.maps {
+		t.Fatalf("unexpected dataset header: typeName=%q maps=%v", got.typeName, got.maps)
+	}
+	wantColumns := []string{"age", "email", "name"}
+	if !reflect.DeepEqual(got.columns, wantColumns) {
+		t.Fatalf("columns mismatch: got %#v, want %#v", got.columns, wantColumns)
+	}
+
+	wantRows := []row{
+		{values: []any{30, MissingKey{}, "Alice"}},
+		{values: []any{MissingKey{}, nil, "Bob"}},
+		{values: []any{nil, nil, nil}, isNil: true},
+	}
+	if !reflect.DeepEqual(got.rows, wantRows) {
+		t.Fatalf("rows mismatch: got %#v, want %#v", got.rows, wantRows)
+	}
+
+	_, err = extract([]map[int]string{{1: "a"}}, options{})
+	if err == nil || !strings.Contains(err.Error(), "expected map with string keys") {
+		t.Fatalf("expected string key error, got %v", err)
+	}
+}
+
+func TestDataset_Align(t *testing.T) {
+	ds := dataset{
+		typeName: "map[string]int",
+		columns:  []string{"a", "c"},
+		rows:     []row{{values: []any{1, 3}}, {values: []any{nil, nil}, isNil: true}},
+		types:    []reflect.Type{reflect.TypeFor[int](), reflect.TypeFor[int]()},
+		maps:     true,
+	}
+
+	columns := unionColumns(ds.columns, []string{"b", "c"})
+	if !reflect.DeepEqual(columns, []string{"a", "b", "c"}) {
+		t.Fatalf("union mismatch: got %#v", columns)
+	}
+
+	got := ds.align(columns)
+	wantRows := []row{
+		{values: []any{1, MissingKey{}, 3}},
+		{values: []any{nil, nil, nil}, isNil: true},
+	}
+	if !reflect.DeepEqual(got.rows, wantRows) {
+		t.Fatalf("rows mismatch: got %#v, want %#v", got.rows, wantRows)
+	}
+	if len(got.types) != 3 {
+		t.Fatalf("expected a type per column, got %d", len(got.types))
+	}
+}
@@ -2,17 +2,21 @@ package datadiff
 
 import (
 	"fmt"
+	"reflect"
+	"strconv"
 	"strings"
-	"text/tabwriter"
 )
 
 // rowStatus indicates the match result for a row pair.
+// Values mirror the exported [RowStatus] constants.
 type rowStatus int
 
 const (
-	rowMatch    rowStatus = iota // rows are equal
-	rowMismatch                  // rows exist at same position but differ
-	rowExtra                     // row exists in one list but not the other
+	rowMatch        rowStatus = iota // rows are equal
+	rowMismatch                      // rows exist at same position but differ
+	rowExtra                         // row exists in one list but not the other
+	rowMissingKey                    // row key exists in one list but not the other
+	rowDuplicateKey                  // row key occurs more than once in a list
 )
 
 // diffResult holds the structured outcome of comparing two datasets.
@@ -21,90 +25,302 @@ type diffResult struct {
 	typeName string
 	columns  []string
 	diffs    []rowDiff
+
+	typeMismatch  bool   // element types differ; rows were not compared
+	otherTypeName string // element type name of listB, if it differs
+
+	schema schemaDiff // columns added, removed or retyped between the lists
+
+	style outputStyle // how values and highlights are rendered
 }
 
 // rowDiff describes the comparison outcome for one row.
 type rowDiff struct {
-	index    int // row index in the original list (-1 for unmatched extras)
+	index    int    // row index in the original list (-1 for unmatched extras)
+	key      string // key label such as "ID=7" when rows are matched by key
 	status   rowStatus
 	valuesA  []any  // field values from listA (nil slice if row missing from A)
 	valuesB  []any  // field values from listB (nil slice if row missing from B)
 	mismatch []bool // per-field: true means values differ (len == len(columns))
+
+	nilA bool // the listA element is a nil pointer
+	nilB bool // the listB element is a nil pointer
 }
 
-const (
-	ansiReset  = "\033[0m"
-	ansiRed    = "\033[31m"
-	ansiGreen  = "\033[32m"
-	ansiYellow = "\033[33m"
-)
+// nilRowLabel is shown in place of the values of a nil element.
+const nilRowLabel = "<nil row>"
 
-// formatDiff renders a diffResult as a human-readable tabular string
-// with ANSI colour highlights.
+// formatDiff renders a diffResult as a human-readable tabular string,
+// with ANSI colour highlights unless result.style is plain.
 func formatDiff(result diffResult) string {
 	if result.equal {
 		return ""
 	}
 
 	var b strings.Builder
-	fmt.Fprintf(&b, "datadiff: []%s are not equal\n\n", result.typeName)
+	if result.typeMismatch {
+		fmt.Fprintf(&b, "datadiff: type mismatch: []%s vs []%s (use CrossType to compare by column name)\n", result.typeName, result.otherTypeName)
+		if !result.schema.empty() {
+			b.WriteString("\n")
+			writeSchema(&b, result.schema, result.style)
+		}
+		return b.String()
+	}
 
-	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	if result.otherTypeName != "" {
+		fmt.Fprintf(&b, "datadiff: []%s and []%s are not equal\n\n", result.typeName, result.otherTypeName)
+	} else {
+		fmt.Fprintf(&b, "datadiff: []%s are not equal\n\n", result.typeName)
+	}
 
-	fmt.Fprint(w, " \t#\t")
-	for _, column := range result.columns {
-		fmt.Fprintf(w, "%s\t", column)
+	if !result.schema.empty() {
+		writeSchema(&b, result.schema, result.style)
 	}
-	fmt.Fprintln(w, "")
 
-	fmt.Fprint(w, "-\t-\t")
-	for range result.columns {
-		fmt.Fprint(w, "-\t")
+	var w table
+	w.add(append([]string{" ", "#"}, result.columns...)...)
+
+	rule := make([]string, len(result.columns)+2)
+	for i := range rule {
+		rule[i] = "-"
 	}
-	fmt.Fprintln(w, "")
+	w.add(rule...)
+
+	shown := result.shownRows()
+	for i := 0; i < len(result.diffs); i++ {
+		if !shown[i] {
+			hidden := 1
+			for i+hidden < len(result.diffs) && !shown[i+hidden] {
+				hidden++
+			}
+			w.addLine(matchingRowsLine(hidden))
+			i += hidden - 1
+			continue
+		}
+
+		diff := result.diffs[i]
+		label := fmt.Sprintf("%d", diff.index)
+		if diff.key != "" {
+			label = diff.key
+		}
 
-	for _, diff := range result.diffs {
 		switch diff.status {
 		case rowMatch:
-			writeRow(w, colorize("✓", ansiGreen), fmt.Sprintf("%d", diff.index), diff.valuesA, nil, result.columns, "")
+			writeRow(&w, result.style, result.columns, tableRow{
+				marker: result.style.colorize("✓", ansiGreen), index: label,
+				values: diff.valuesA, isNil: diff.nilA,
+			})
 		case rowMismatch:
-			writeRow(w, colorize("✗", ansiRed), fmt.Sprintf("%d", diff.index), diff.valuesA, diff.mismatch, result.columns, "← expected")
-			writeRow(w, "", "", diff.valuesB, diff.mismatch, result.columns, "← actual")
+			writeRow(&w, result.style, result.columns, tableRow{
+				marker: result.style.colorize("✗", ansiRed), index: label,
+				values: diff.valuesA, isNil: diff.nilA, other: diff.valuesB, otherNil: diff.nilB,
+				mismatch: diff.mismatch,
+				note:     "← expected" + missingKeys(diff.valuesA, diff.mismatch, result.columns),
+			})
+			writeRow(&w, result.style, result.columns, tableRow{
+				values: diff.valuesB, isNil: diff.nilB, other: diff.valuesA, otherNil: diff.nilA,
+				actual: true, mismatch: diff.mismatch,
+				note: "← actual" + missingKeys(diff.valuesB, diff.mismatch, result.columns),
+			})
 		case rowExtra:
-			values := diff.valuesA
-			note := "← extra in expected"
-			if values == nil {
-				values = diff.valuesB
-				note = "← extra in actual"
+			values, isNil, side := diff.sideValues()
+			writeRow(&w, result.style, result.columns, tableRow{
+				marker: result.style.colorize("+", ansiYellow), index: label,
+				values: values, isNil: isNil, note: "← extra in " + side,
+			})
+		case rowMissingKey:
+			values, isNil, side := diff.sideValues()
+			writeRow(&w, result.style, result.columns, tableRow{
+				marker: result.style.colorize("+", ansiYellow), index: label,
+				values: values, isNil: isNil, note: "← key only in " + side,
+			})
+		case rowDuplicateKey:
+			values, isNil, side := diff.sideValues()
+			writeRow(&w, result.style, result.columns, tableRow{
+				marker: result.style.colorize("!", ansiRed), index: label,
+				values: values, isNil: isNil, note: "← duplicate key in " + side,
+			})
+		}
+	}
+
+	w.render(&b)
+	writeDetails(&b, result)
+	return b.String()
+}
+
+// shownRows reports, for each entry of result.diffs, whether it is listed
+// in the diff table. When result.style.collapse is set, matching rows more
+// than result.style.context rows from a difference are hidden, unless
+// hiding them would save no lines.
+func (result diffResult) shownRows() []bool {
+	shown := make([]bool, len(result.diffs))
+	for i, diff := range result.diffs {
+		if !result.style.collapse || diff.status != rowMatch {
+			for j := max(i-result.style.context, 0); j <= min(i+result.style.context, len(shown)-1); j++ {
+				shown[j] = true
 			}
-			writeRow(w, colorize("+", ansiYellow), fmt.Sprintf("%d", diff.index), values, nil, result.columns, note)
 		}
 	}
 
-	_ = w.Flush()
+	// A single hidden row would take a line of its own to mention.
+	for i := range shown {
+		if !shown[i] && (i == 0 || shown[i-1]) && (i == len(shown)-1 || shown[i+1]) {
+			shown[i] = true
+		}
+	}
+	return shown
+}
+
+// matchingRowsLine returns the line that stands in for n hidden matching
+// rows, such as "… 4,812 matching rows …".
+func matchingRowsLine(n int) string {
+	return fmt.Sprintf("%s %s matching rows %s", ellipsis, groupDigits(n), ellipsis)
+}
+
+// groupDigits formats n with commas between groups of three digits.
+func groupDigits(n int) string {
+	digits := strconv.Itoa(n)
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 && digits[i-1] != '-' {
+			b.WriteByte(',')
+		}
+		b.WriteRune(d)
+	}
 	return b.String()
 }
 
-func writeRow(w *tabwriter.Writer, marker, index string, values []any, mismatch []bool, columns []string, note string) {
-	fmt.Fprintf(w, "%s\t%s\t", marker, index)
+// writeSchema writes the schema differences section shown above the
+// row table: removed, added and retyped columns with their types.
+func writeSchema(b *strings.Builder, schema schemaDiff, style outputStyle) {
+	b.WriteString("schema differences:\n")
+
+	var w table
+	for _, column := range schema.removed {
+		w.add("  "+style.colorize("-", ansiRed), column.name, typeString(column.typeA), "← only in expected")
+	}
+	for _, column := range schema.added {
+		w.add("  "+style.colorize("+", ansiYellow), column.name, typeString(column.typeB), "← only in actual")
+	}
+	for _, column := range schema.retyped {
+		w.add("  "+style.colorize("~", ansiYellow), column.name, typeString(column.typeA)+" → "+typeString(column.typeB), "← retyped")
+	}
+	w.render(b)
+
+	b.WriteString("\n")
+}
+
+// typeString returns the name of t, or "" if t is nil.
+func typeString(t reflect.Type) string {
+	if t == nil {
+		return ""
+	}
+	return t.String()
+}
+
+// sideValues returns the values of a one-sided row, whether it is a nil
+// row, and the name of the list it came from.
+func (d rowDiff) sideValues() ([]any, bool, string) {
+	if d.valuesA == nil {
+		return d.valuesB, d.nilB, "actual"
+	}
+	return d.valuesA, d.nilA, "expected"
+}
+
+// missingKeys returns a note suffix naming the mismatched columns whose
+// keys are absent from a map row, or "" if there are none.
+func missingKeys(values []any, mismatch []bool, columns []string) string {
+	var missing []string
+	for i, value := range values {
+		if _, ok := value.(MissingKey); ok && i < len(mismatch) && mismatch[i] {
+			missing = append(missing, columns[i])
+		}
+	}
+	if len(missing) == 0 {
+		return ""
+	}
+	return ", missing keys: " + strings.Join(missing, ", ")
+}
+
+// tableRow is one row of the diff table before rendering.
+type tableRow struct {
+	marker, index string
+	values        []any
+	isNil         bool
+
+	// other and otherNil describe the paired row of a mismatched pair;
+	// actual is set on the row from listB.
+	other    []any
+	otherNil bool
+	actual   bool
+
+	mismatch []bool
+	note     string
+}
+
+// writeRow writes one table row. Cells are rendered, elided and
+// highlighted according to style. Long mismatched cells show where they
+// differ from the paired row (see [inlineDiff]), and mismatched numeric
+// cells of the actual row show their delta from the expected value. A nil
+// row is written as [nilRowLabel] in its first cell, highlighted if the
+// row is mismatched.
+func writeRow(w *table, style outputStyle, columns []string, row tableRow) {
+	cells := make([]string, 0, len(columns)+3)
+	cells = append(cells, row.marker, row.index)
 
 	for i := 0; i < len(columns); i++ {
 		value := ""
-		if i < len(values) {
-			value = fmt.Sprintf("%v", values[i])
-			if i < len(mismatch) && mismatch[i] {
-				value = colorize(value, ansiRed)
+		switch {
+		case row.isNil:
+			if i == 0 {
+				value = nilRowLabel
+				if len(row.mismatch) > 0 {
+					value = style.mismatched(value)
+				}
+			}
+		case i < len(row.values) && i < len(row.mismatch) && row.mismatch[i]:
+			value = style.mismatched(row.mismatchedCell(style, i))
+			if row.actual && !row.otherNil && i < len(row.other) {
+				if delta, ok := numericDelta(row.other[i], row.values[i], style.cells); ok {
+					value += " (" + delta + ")"
+				}
 			}
+		case i < len(row.values):
+			value = elide(singleLine(style.cells.format(row.values[i])), style.maxWidth)
 		}
-		fmt.Fprintf(w, "%s\t", value)
+		cells = append(cells, value)
+	}
+
+	if row.note != "" {
+		cells = append(cells, row.note)
+	}
+	w.add(cells...)
+}
+
+// mismatchedCell returns the text of mismatched cell i, before
+// highlighting. When either side is too wide for the cell, it shows only
+// the region around the change.
+func (row tableRow) mismatchedCell(style outputStyle, i int) string {
+	text := singleLine(style.cells.format(row.values[i]))
+	if style.maxWidth <= 0 || row.otherNil || i >= len(row.other) {
+		return elide(text, style.maxWidth)
+	}
+
+	otherText := singleLine(style.cells.format(row.other[i]))
+	if displayWidth(text) <= style.maxWidth && displayWidth(otherText) <= style.maxWidth {
+		return text
 	}
 
-	if note != "" {
-		fmt.Fprintf(w, "%s\t", note)
+	if row.actual {
+		_, actual := inlineDiff(otherText, text, style.maxWidth)
+		return actual
 	}
-	fmt.Fprintln(w, "")
+	expected, _ := inlineDiff(text, otherText, style.maxWidth)
+	return expected
 }
 
-func colorize(value, color string) string {
-	return color + value + ansiReset
+// singleLine escapes line breaks in a cell so that it stays on one table
+// row; multi-line values are shown in full in the details section.
+func singleLine(text string) string {
+	return strings.NewReplacer("\r", `\r`, "\n", `\n`).Replace(text)
 }
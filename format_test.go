@@ -1,11 +1,18 @@
 package datadiff
 
 import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 )
 
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
 var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
 
 func stripANSI(s string) string {
@@ -173,3 +180,362 @@ func TestFormatDiff_EmptyDiff(t *testing.T) {
 		t.Fatalf("expected empty output, got %q", got)
 	}
 }
+
+func TestFormatDiff_KeyedRows(t *testing.T) {
+	result := diffResult{
+		equal:    false,
+		typeName: "User",
+		columns:  []string{"ID", "Age"},
+		diffs: []rowDiff{
+			{index: 0, key: "ID=7", status: rowMismatch, valuesA: []any{7, 30}, valuesB: []any{7, 31}, mismatch: []bool{false, true}},
+			{index: 1, key: "ID=8", status: rowMissingKey, valuesA: []any{8, 40}},
+			{index: 1, key: "ID=9", status: rowMissingKey, valuesB: []any{9, 50}},
+			{index: 2, key: "ID=1", status: rowDuplicateKey, valuesA: []any{1, 20}},
+		},
+	}
+
+	got := stripANSI(formatDiff(result))
+
+	for _, want := range []string{"ID=7", "← key only in expected", "← key only in actual", "← duplicate key in expected"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %q in output, got %q", want, got)
+		}
+	}
+}
+
+func TestFormatDiff_NumericDelta(t *testing.T) {
+	result := diffResult{
+		equal:    false,
+		typeName: "Order",
+		columns:  []string{"ID", "Total"},
+		diffs: []rowDiff{
+			{index: 0, status: rowMismatch, valuesA: []any{1, 10.5}, valuesB: []any{1, 10.25}, mismatch: []bool{false, true}},
+		},
+	}
+
+	got := stripANSI(formatDiff(result))
+	if !strings.Contains(got, "10.25 (-0.25)") {
+		t.Fatalf("expected delta on actual value, got %q", got)
+	}
+	if strings.Contains(got, "10.5 (") {
+		t.Fatalf("expected no delta on expected value, got %q", got)
+	}
+
+	result.columns = []string{"ID", "Timeout"}
+	result.diffs[0].valuesA = []any{1, time.Second}
+	result.diffs[0].valuesB = []any{1, 2 * time.Second}
+	if got := stripANSI(formatDiff(result)); !strings.Contains(got, "2s (+1s)") {
+		t.Fatalf("expected delta formatted as a duration, got %q", got)
+	}
+}
+
+func TestFormatDiff_LongCells(t *testing.T) {
+	type Note struct {
+		ID   int
+		Text string
+		Tag  string
+	}
+	a := []Note{{ID: 1, Text: "The quick brown fox jumps over the lazy dog near New York City at dawn", Tag: "a very long tag that nobody needs to read in full"}}
+	b := []Note{{ID: 1, Text: "The quick brown fox jumps over the lazy dog near New Yark City at dawn", Tag: "a very long tag that nobody needs to read in full"}}
+
+	result, err := Diff(a, b, WithColor(ColorNever))
+	if err != nil {
+		t.Fatalf("Diff returned unexpected error: %v", err)
+	}
+	got := result.String()
+	if !strings.Contains(got, "*…lazy dog near New Y[o→a]rk City at dawn*") {
+		t.Fatalf("expected inline diff, got %q", got)
+	}
+	if !strings.Contains(got, "a very long tag that nobody needs to re…") {
+		t.Fatalf("expected elided cell, got %q", got)
+	}
+
+	result, err = Diff(a, b, WithColor(ColorNever), WithMaxCellWidth(0))
+	if err != nil {
+		t.Fatalf("Diff returned unexpected error: %v", err)
+	}
+	if got := result.String(); !strings.Contains(got, "*"+b[0].Text+"*") {
+		t.Fatalf("expected full values without a width limit, got %q", got)
+	}
+}
+
+func TestFormatDiff_NilRows(t *testing.T) {
+	result := diffResult{
+		equal:    false,
+		typeName: "*Person",
+		columns:  []string{"Name", "Age"},
+		diffs: []rowDiff{
+			{index: 0, status: rowMismatch, valuesA: []any{"Alice", 30}, valuesB: []any{nil, nil}, nilB: true, mismatch: []bool{true, true}},
+			{index: 1, status: rowExtra, valuesA: []any{nil, nil}, nilA: true},
+		},
+	}
+
+	got := stripANSI(formatDiff(result))
+
+	for _, want := range []string{"[]*Person are not equal", "<nil row>", "← actual", "← extra in expected"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %q in output, got %q", want, got)
+		}
+	}
+	if strings.Contains(got, "<nil>") {
+		t.Fatalf("expected nil row cells to be blank, got %q", got)
+	}
+}
+
+func TestFormatDiff_MissingKeys(t *testing.T) {
+	result := diffResult{
+		equal:    false,
+		typeName: "map[string]any",
+		columns:  []string{"email", "name"},
+		diffs: []rowDiff{
+			{index: 0, status: rowMismatch, valuesA: []any{"a@example.com", "Alice"}, valuesB: []any{MissingKey{}, "Alice"}, mismatch: []bool{true, false}},
+		},
+	}
+
+	got := stripANSI(formatDiff(result))
+
+	for _, want := range []string{"[]map[string]any are not equal", "<missing>", "← actual, missing keys: email"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %q in output, got %q", want, got)
+		}
+	}
+	if strings.Contains(got, "← expected, missing keys") {
+		t.Fatalf("expected no missing keys note on the expected row, got %q", got)
+	}
+}
+
+func TestFormatDiff_CrossTypeSchema(t *testing.T) {
+	result := diffResult{
+		equal:         false,
+		typeName:      "Person",
+		otherTypeName: "PersonDTO",
+		columns:       []string{"Name"},
+		diffs:         []rowDiff{{index: 0, status: rowMatch, valuesA: []any{"Alice"}, valuesB: []any{"Alice"}}},
+		schema: schemaDiff{
+			removed: []schemaColumn{{name: "Email", typeA: reflect.TypeFor[string]()}},
+			added:   []schemaColumn{{name: "Phone", typeB: reflect.TypeFor[string]()}},
+			retyped: []schemaColumn{{name: "Age", typeA: reflect.TypeFor[int](), typeB: reflect.TypeFor[int64]()}},
+		},
+	}
+
+	got := stripANSI(formatDiff(result))
+
+	for _, want := range []string{
+		"[]Person and []PersonDTO are not equal",
+		"schema differences:",
+		"Email  string       ← only in expected",
+		"Phone  string       ← only in actual",
+		"Age    int → int64  ← retyped",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %q in output, got %q", want, got)
+		}
+	}
+	if strings.Index(got, "schema differences:") > strings.Index(got, "Name") {
+		t.Fatalf("expected schema section before the row table, got %q", got)
+	}
+}
+
+func TestFormatDiff_TypeMismatchSchema(t *testing.T) {
+	result := diffResult{
+		typeName:      "Person",
+		typeMismatch:  true,
+		otherTypeName: "Employee",
+		schema:        schemaDiff{added: []schemaColumn{{name: "Salary"}}},
+	}
+
+	got := stripANSI(formatDiff(result))
+	for _, want := range []string{"type mismatch: []Person vs []Employee", "schema differences:", "Salary"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %q in output, got %q", want, got)
+		}
+	}
+}
+
+// assertGolden compares got with testdata/name.golden, rewriting the file
+// instead when the -update flag is set.
+func assertGolden(t *testing.T, name, got string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file (run with -update to create it): %v", err)
+	}
+	if got != string(want) {
+		t.Fatalf("output does not match %s:\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func TestFormatDiff_Golden(t *testing.T) {
+	mixedWidth := diffResult{
+		equal:    false,
+		typeName: "City",
+		columns:  []string{"Name", "Country", "Landmark"},
+		diffs: []rowDiff{
+			{index: 0, status: rowMatch, valuesA: []any{"Boston", "USA", "Fenway Park"}, valuesB: []any{"Boston", "USA", "Fenway Park"}},
+			{index: 1, status: rowMismatch, valuesA: []any{"東京", "日本", "東京タワー"}, valuesB: []any{"東京", "日本", "スカイツリー"}, mismatch: []bool{false, false, true}},
+			{index: 2, status: rowMismatch, valuesA: []any{"서울", "대한민국", "🏯 Gyeongbokgung"}, valuesB: []any{"Seoul", "대한민국", "🏯 Gyeongbokgung"}, mismatch: []bool{true, false, false}},
+			{index: 3, status: rowExtra, valuesB: []any{"Zürich", "Schweiz", "🏔️ Alps"}},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		plain bool
+	}{
+		{name: "mixed_width_color"},
+		{name: "mixed_width_plain", plain: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := mixedWidth
+			result.style.plain = tt.plain
+
+			got := formatDiff(result)
+			if tt.plain && strings.Contains(got, "\033[") {
+				t.Fatalf("expected no ANSI escapes in plain output, got %q", got)
+			}
+			// Colors are stripped so that the golden file shows the
+			// alignment a terminal would display.
+			assertGolden(t, tt.name, stripANSI(got))
+		})
+	}
+}
+
+func TestFormatDiff_Details(t *testing.T) {
+	type Address struct {
+		Street string
+		City   string
+	}
+	type Customer struct {
+		ID      int
+		Name    string
+		Address Address
+		Tags    []string
+		Notes   string
+	}
+	a := []Customer{
+		{ID: 1, Name: "Alice", Address: Address{"1 Main St", "Boston"}, Tags: []string{"vip"}, Notes: "call first\nprefers email"},
+		{ID: 2, Name: "Bob", Address: Address{"2 Elm St", "Denver"}, Tags: []string{"new"}},
+	}
+	b := []Customer{
+		{ID: 1, Name: "Alice", Address: Address{"1 Main St", "Cambridge"}, Tags: []string{"vip", "beta"}, Notes: "call first\nprefers phone"},
+		{ID: 2, Name: "Robert", Address: Address{"2 Elm St", "Denver"}, Tags: []string{"new"}},
+	}
+
+	result, err := Diff(a, b, WithColor(ColorNever), WithMaxDepth(0), WithKeys("ID"))
+	if err != nil {
+		t.Fatalf("Diff returned unexpected error: %v", err)
+	}
+	assertGolden(t, "details_plain", result.String())
+
+	result, err = Diff(a[1:], b[1:], WithColor(ColorNever))
+	if err != nil {
+		t.Fatalf("Diff returned unexpected error: %v", err)
+	}
+	if got := result.String(); strings.Contains(got, "details:") {
+		t.Fatalf("expected no details section for scalar mismatches, got %q", got)
+	}
+}
+
+func TestFormatDiff_Context(t *testing.T) {
+	diffs := make([]rowDiff, 12)
+	for i := range diffs {
+		diffs[i] = rowDiff{index: i, status: rowMatch, valuesA: []any{i}, valuesB: []any{i}}
+	}
+	diffs[5] = rowDiff{index: 5, status: rowMismatch, valuesA: []any{5}, valuesB: []any{50}, mismatch: []bool{true}}
+	diffs[8] = rowDiff{index: 8, status: rowExtra, valuesB: []any{8}}
+	result := diffResult{
+		typeName: "Row",
+		columns:  []string{"N"},
+		diffs:    diffs,
+		style:    outputStyle{plain: true, collapse: true, context: 1},
+	}
+
+	want := strings.Join([]string{
+		"datadiff: []Row are not equal",
+		"",
+		"   #  N",
+		"-  -  -",
+		"… 4 matching rows …",
+		"✓  4  4",
+		"✗  5  *5*         ← expected",
+		"      *50* (+45)  ← actual",
+		"✓  6  6",
+		"✓  7  7",
+		"+  8  8           ← extra in actual",
+		"✓  9  9",
+		"… 2 matching rows …",
+		"",
+	}, "\n")
+	if got := formatDiff(result); got != want {
+		t.Fatalf("formatDiff mismatch:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+
+	// A lone matching row is shown rather than replaced by a line.
+	result.style.context = 4
+	if got := formatDiff(result); strings.Contains(got, "matching row") || !strings.Contains(got, "✓  0   0") {
+		t.Fatalf("expected every row to be shown, got:\n%s", got)
+	}
+
+	result.style.collapse = false
+	result.style.context = 0
+	if got := formatDiff(result); strings.Contains(got, "matching rows") || !strings.Contains(got, "✓  0   0") {
+		t.Fatalf("expected every row without collapsing, got:\n%s", got)
+	}
+}
+
+func TestFormatDiff_ContextOptions(t *testing.T) {
+	a := make([]Person, 20)
+	for i := range a {
+		a[i] = Person{Name: "P", Age: i}
+	}
+	b := append([]Person(nil), a...)
+	b[10].Age = 99
+
+	tests := []struct {
+		name      string
+		flags     []any
+		wantLines []string
+	}{
+		{name: "default", flags: nil, wantLines: []string{"… 7 matching rows …", "✓  7   P     7", "… 6 matching rows …"}},
+		{name: "context", flags: []any{WithContext(0)}, wantLines: []string{"… 10 matching rows …", "… 9 matching rows …"}},
+		{name: "all rows", flags: []any{ShowAllRows}, wantLines: []string{"✓  0   P     0", "✓  19  P     19"}},
+		{name: "all rows option", flags: []any{WithShowAllRows()}, wantLines: []string{"✓  0   P     0", "✓  19  P     19"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Diff(a, b, append(tt.flags, WithColor(ColorNever))...)
+			if err != nil {
+				t.Fatalf("Diff returned unexpected error: %v", err)
+			}
+			got := result.String()
+			for _, line := range tt.wantLines {
+				if !strings.Contains(got, line) {
+					t.Fatalf("expected %q in output:\n%s", line, got)
+				}
+			}
+		})
+	}
+}
+
+func TestGroupDigits(t *testing.T) {
+	for n, want := range map[int]string{0: "0", 12: "12", 999: "999", 1000: "1,000", 4812: "4,812", 1234567: "1,234,567", -4812: "-4,812"} {
+		if got := groupDigits(n); got != want {
+			t.Fatalf("groupDigits(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
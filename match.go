@@ -0,0 +1,316 @@
+package datadiff
+
+import (
+	"hash/maphash"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// exactPairs pairs rows of a with identical rows of b in O(n) by bucketing
+// rows on a hash of their values. Each row of a, in order, takes the first
+// unpaired identical row of b. It returns, for each row of a, the index of
+// its counterpart in b or -1 if no identical row is left.
+func exactPairs(a, b dataset, m *matcher) []int {
+	seed := maphash.MakeSeed()
+
+	buckets := make(map[uint64][]int, len(b.rows))
+	for j := range b.rows {
+		h := m.rowHash(seed, b.rows[j].values)
+		buckets[h] = append(buckets[h], j)
+	}
+
+	pairs := make([]int, len(a.rows))
+	for i := range a.rows {
+		pairs[i] = -1
+
+		h := m.rowHash(seed, a.rows[i].values)
+		bucket := buckets[h]
+		for k, j := range bucket {
+			// Equal hashes do not imply equal rows; confirm before pairing.
+			if _, mismatchCount := m.rowMismatch(a.rows[i], b.rows[j]); mismatchCount == 0 {
+				pairs[i] = j
+				buckets[h] = append(bucket[:k], bucket[k+1:]...)
+				break
+			}
+		}
+	}
+
+	return pairs
+}
+
+// rowHash hashes the values of a row so that rows m finds equal hash
+// equally. Columns with a comparer are left out, and values only m can
+// tell equal, such as floats under a tolerance or values of a type with
+// an Equal method, are hashed by their type alone, so that rows still
+// bucket on their other columns. Other scalars are hashed by value, and
+// other values, such as structs, maps and slices, by their type and
+// length. This may put unequal rows in one bucket but never pairs them,
+// because callers confirm every candidate; not walking structs, maps and
+// slices also keeps cyclic ones from recursing.
+func (m *matcher) rowHash(seed maphash.Seed, values []any) uint64 {
+	var h maphash.Hash
+	h.SetSeed(seed)
+
+	var buf []byte
+	for i, value := range values {
+		buf = buf[:0]
+		if i >= len(m.overrides) || m.overrides[i] == nil {
+			buf = m.appendValueHash(buf, i, value)
+		}
+		buf = append(buf, 0)
+		_, _ = h.Write(buf)
+	}
+
+	return h.Sum64()
+}
+
+// appendValueHash appends the hashed form of v, a value of the given
+// column, to buf for [matcher.rowHash].
+func (m *matcher) appendValueHash(buf []byte, column int, v any) []byte {
+	if v == nil {
+		return append(buf, "nil"...)
+	}
+	rv := reflect.ValueOf(v)
+	typ := rv.Type()
+	if m.typeEqual(typ) != nil {
+		return append(buf, typ.String()...)
+	}
+
+	// Numbers, strings and booleans compared by value across types hash
+	// by their class rather than their type.
+	kind := numberKind(rv.Kind())
+	switch {
+	case m.byValue && kind != 0:
+		buf = append(buf, 'n')
+	case m.byValue && (rv.Kind() == reflect.String || rv.Kind() == reflect.Bool):
+		buf = append(buf, rv.Kind().String()...)
+	default:
+		buf = append(buf, typ.String()...)
+	}
+	buf = append(buf, ':')
+
+	switch {
+	case kind == 'f' || m.byValue && kind != 0:
+		if column < len(m.tolerances) && m.tolerances[column] != (tolerance{}) {
+			return buf
+		}
+		f := numberFloat(rv)
+		switch {
+		case math.IsNaN(f):
+			return append(buf, "NaN"...)
+		case f == 0:
+			f = 0 // -0 equals 0
+		}
+		return strconv.AppendUint(buf, math.Float64bits(f), 16)
+	case kind == 'i':
+		return strconv.AppendInt(buf, rv.Int(), 10)
+	case kind == 'u':
+		return strconv.AppendUint(buf, rv.Uint(), 10)
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return strconv.AppendBool(buf, rv.Bool())
+	case reflect.Complex64, reflect.Complex128:
+		c := rv.Complex()
+		buf = strconv.AppendUint(buf, math.Float64bits(real(c)), 16)
+		return strconv.AppendUint(append(buf, ','), math.Float64bits(imag(c)), 16)
+	case reflect.String:
+		return append(buf, rv.String()...)
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return strconv.AppendInt(buf, int64(rv.Len()), 10)
+	}
+	return buf
+}
+
+// pairFuzzy completes pairs, as returned by [exactPairs], by matching the
+// still-unpaired rows of a and b with greedy or optimal best-fit.
+func pairFuzzy(pairs []int, a, b dataset, m *matcher, optimal bool) {
+	pairedB := make([]bool, len(b.rows))
+	var leftA, leftB []int
+	for i, j := range pairs {
+		if j < 0 {
+			leftA = append(leftA, i)
+		} else {
+			pairedB[j] = true
+		}
+	}
+	for j := range b.rows {
+		if !pairedB[j] {
+			leftB = append(leftB, j)
+		}
+	}
+	if len(leftA) == 0 || len(leftB) == 0 {
+		return
+	}
+
+	subset := func(ds dataset, indexes []int) dataset {
+		sub := dataset{typeName: ds.typeName, columns: ds.columns, rows: make([]row, len(indexes))}
+		for k, index := range indexes {
+			sub.rows[k] = ds.rows[index]
+		}
+		return sub
+	}
+
+	subA, subB := subset(a, leftA), subset(b, leftB)
+	var subPairs []int
+	if optimal {
+		subPairs = optimalPairs(subA, subB, m)
+	} else {
+		subPairs = greedyPairs(subA, subB, m)
+	}
+
+	for k, j := range subPairs {
+		if j >= 0 {
+			pairs[leftA[k]] = leftB[j]
+		}
+	}
+}
+
+// greedyPairs matches each row of a, in order, to the first unpaired row
+// of b with the fewest mismatched fields. It returns, for each row of a,
+// the index of its counterpart in b or -1 if b has run out of rows.
+func greedyPairs(a, b dataset, m *matcher) []int {
+	pairs := make([]int, len(a.rows))
+	unpaired := make([]int, len(b.rows))
+	for j := range b.rows {
+		unpaired[j] = j
+	}
+
+	for i, rowA := range a.rows {
+		pairs[i] = -1
+		if len(unpaired) == 0 {
+			continue
+		}
+
+		best := 0
+		bestMismatchCount := len(m.columns) + 1
+		for k, j := range unpaired {
+			_, mismatchCount := m.rowMismatch(rowA, b.rows[j])
+			if mismatchCount < bestMismatchCount {
+				best = k
+				bestMismatchCount = mismatchCount
+			}
+			if mismatchCount == 0 {
+				break
+			}
+		}
+
+		pairs[i] = unpaired[best]
+		unpaired = append(unpaired[:best], unpaired[best+1:]...)
+	}
+
+	return pairs
+}
+
+// optimalPairs matches rows of a to rows of b so that the total number of
+// mismatched fields across all pairs is minimal. As many rows as possible
+// are paired; the surplus rows of the longer list map to -1.
+func optimalPairs(a, b dataset, m *matcher) []int {
+	pairs := make([]int, len(a.rows))
+	for i := range pairs {
+		pairs[i] = -1
+	}
+	if len(a.rows) == 0 || len(b.rows) == 0 {
+		return pairs
+	}
+
+	transposed := len(a.rows) > len(b.rows)
+	rows, cols := a, b
+	if transposed {
+		rows, cols = b, a
+	}
+
+	cost := make([][]int, len(rows.rows))
+	for i := range rows.rows {
+		cost[i] = make([]int, len(cols.rows))
+		for j := range cols.rows {
+			_, cost[i][j] = m.rowMismatch(rows.rows[i], cols.rows[j])
+		}
+	}
+
+	for i, j := range minCostAssignment(cost, len(cols.rows)) {
+		if transposed {
+			pairs[j] = i
+		} else {
+			pairs[i] = j
+		}
+	}
+
+	return pairs
+}
+
+// minCostAssignment solves the rectangular assignment problem with the
+// Hungarian algorithm in O(n²·m). cost has n rows of m columns, n <= m.
+// It returns the column assigned to each row.
+func minCostAssignment(cost [][]int, m int) []int {
+	n := len(cost)
+	const inf = int(^uint(0) >> 1)
+
+	// Potentials and matching use 1-based indexes; column 0 is a sentinel.
+	u := make([]int, n+1)
+	v := make([]int, m+1)
+	match := make([]int, m+1) // match[j] is the row assigned to column j
+	way := make([]int, m+1)
+
+	for i := 1; i <= n; i++ {
+		match[0] = i
+		j0 := 0
+		minv := make([]int, m+1)
+		used := make([]bool, m+1)
+		for j := range minv {
+			minv[j] = inf
+		}
+
+		for {
+			used[j0] = true
+			i0 := match[j0]
+			delta := inf
+			j1 := 0
+			for j := 1; j <= m; j++ {
+				if used[j] {
+					continue
+				}
+				reduced := cost[i0-1][j-1] - u[i0] - v[j]
+				if reduced < minv[j] {
+					minv[j] = reduced
+					way[j] = j0
+				}
+				if minv[j] < delta {
+					delta = minv[j]
+					j1 = j
+				}
+			}
+
+			for j := 0; j <= m; j++ {
+				if used[j] {
+					u[match[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+
+			j0 = j1
+			if match[j0] == 0 {
+				break
+			}
+		}
+
+		for j0 != 0 {
+			j1 := way[j0]
+			match[j0] = match[j1]
+			j0 = j1
+		}
+	}
+
+	assignment := make([]int, n)
+	for j := 1; j <= m; j++ {
+		if match[j] != 0 {
+			assignment[match[j]-1] = j - 1
+		}
+	}
+
+	return assignment
+}
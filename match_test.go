@@ -0,0 +1,378 @@
+package datadiff
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// adversarialDatasets returns inputs where greedy matching pairs the first
+// expected row with its closest candidate and forces a worse total.
+//
+//	cost   B0  B1
+//	A0      1   2
+//	A1      1   3
+//
+// Greedy pairs A0-B0, A1-B1 (total 4); the optimum is A0-B1, A1-B0 (total 3).
+func adversarialDatasets() (dataset, dataset) {
+	columns := []string{"X", "Y", "Z"}
+	a := makeDataset("Point", columns,
+		[]any{0, 0, 0},
+		[]any{1, 0, 2},
+	)
+	b := makeDataset("Point", columns,
+		[]any{1, 0, 0},
+		[]any{0, 1, 1},
+	)
+	return a, b
+}
+
+func totalMismatchCount(result diffResult) int {
+	total := 0
+	for _, diff := range result.diffs {
+		for _, mismatch := range diff.mismatch {
+			if mismatch {
+				total++
+			}
+		}
+	}
+	return total
+}
+
+func TestGreedyPairs_Adversarial(t *testing.T) {
+	a, b := adversarialDatasets()
+
+	got := greedyPairs(a, b, newMatcher(a.columns, options{}))
+	if got[0] != 0 || got[1] != 1 {
+		t.Fatalf("greedy pairs mismatch: got %v, want [0 1]", got)
+	}
+}
+
+func TestOptimalPairs_Adversarial(t *testing.T) {
+	a, b := adversarialDatasets()
+
+	got := optimalPairs(a, b, newMatcher(a.columns, options{}))
+	if got[0] != 1 || got[1] != 0 {
+		t.Fatalf("optimal pairs mismatch: got %v, want [1 0]", got)
+	}
+
+	greedy := compare(a, b, options{ignoreOrder: true})
+	optimal := compare(a, b, options{ignoreOrder: true, optimalMatching: true})
+	if totalMismatchCount(greedy) != 4 {
+		t.Fatalf("greedy total mismatch count: got %d, want %d", totalMismatchCount(greedy), 4)
+	}
+	if totalMismatchCount(optimal) != 3 {
+		t.Fatalf("optimal total mismatch count: got %d, want %d", totalMismatchCount(optimal), 3)
+	}
+}
+
+func TestOptimalPairs_OrderIndependent(t *testing.T) {
+	columns := []string{"A", "B", "C", "D"}
+	rng := rand.New(rand.NewSource(1))
+
+	randomRows := func(n int) [][]any {
+		rows := make([][]any, n)
+		for i := range rows {
+			rows[i] = []any{rng.Intn(2), rng.Intn(2), rng.Intn(3), rng.Intn(3)}
+		}
+		return rows
+	}
+
+	rowsA := randomRows(7)
+	rowsB := randomRows(7)
+	want := totalMismatchCount(compare(
+		makeDataset("Row", columns, rowsA...),
+		makeDataset("Row", columns, rowsB...),
+		options{ignoreOrder: true, optimalMatching: true},
+	))
+
+	for trial := 0; trial < 20; trial++ {
+		rng.Shuffle(len(rowsA), func(i, j int) { rowsA[i], rowsA[j] = rowsA[j], rowsA[i] })
+		rng.Shuffle(len(rowsB), func(i, j int) { rowsB[i], rowsB[j] = rowsB[j], rowsB[i] })
+
+		got := totalMismatchCount(compare(
+			makeDataset("Row", columns, rowsA...),
+			makeDataset("Row", columns, rowsB...),
+			options{ignoreOrder: true, optimalMatching: true},
+		))
+		if got != want {
+			t.Fatalf("trial %d: total mismatch count depends on row order: got %d, want %d", trial, got, want)
+		}
+	}
+}
+
+func TestOptimalPairs_Rectangular(t *testing.T) {
+	columns := []string{"Name", "Age"}
+	a := makeDataset("Person", columns,
+		[]any{"Alice", 30},
+		[]any{"Bob", 25},
+		[]any{"Charlie", 35},
+	)
+	b := makeDataset("Person", columns,
+		[]any{"Charlie", 36},
+		[]any{"Alice", 30},
+	)
+
+	got := optimalPairs(a, b, newMatcher(a.columns, options{}))
+	want := []int{1, -1, 0}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("pairs mismatch: got %v, want %v", got, want)
+		}
+	}
+
+	got = optimalPairs(b, a, newMatcher(b.columns, options{}))
+	want = []int{2, 0}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("transposed pairs mismatch: got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestMinCostAssignment_BruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+
+	for trial := 0; trial < 50; trial++ {
+		n := 1 + rng.Intn(4)
+		m := n + rng.Intn(3)
+		cost := make([][]int, n)
+		for i := range cost {
+			cost[i] = make([]int, m)
+			for j := range cost[i] {
+				cost[i][j] = rng.Intn(6)
+			}
+		}
+
+		assignment := minCostAssignment(cost, m)
+		got := 0
+		seen := make(map[int]bool)
+		for i, j := range assignment {
+			if seen[j] {
+				t.Fatalf("trial %d: column %d assigned twice in %v", trial, j, assignment)
+			}
+			seen[j] = true
+			got += cost[i][j]
+		}
+
+		if want := bruteForceMinCost(cost, 0, make([]bool, m)); got != want {
+			t.Fatalf("trial %d: assignment cost mismatch: got %d, want %d (cost %v)", trial, got, want, cost)
+		}
+	}
+}
+
+func bruteForceMinCost(cost [][]int, row int, used []bool) int {
+	if row == len(cost) {
+		return 0
+	}
+
+	best := -1
+	for j := range used {
+		if used[j] {
+			continue
+		}
+		used[j] = true
+		total := cost[row][j] + bruteForceMinCost(cost, row+1, used)
+		used[j] = false
+		if best < 0 || total < best {
+			best = total
+		}
+	}
+	return best
+}
+
+func TestExactPairs(t *testing.T) {
+	a := makePersonDataset(
+		[]any{"X", 1},
+		[]any{"Y", 2},
+		[]any{"X", 1},
+		[]any{"Z", 3},
+	)
+	b := makePersonDataset(
+		[]any{"X", 1},
+		[]any{"Z", 4},
+		[]any{"Y", 2},
+		[]any{"X", 1},
+	)
+
+	got := exactPairs(a, b, newMatcher(a.columns, options{}))
+	want := []int{0, 2, 3, -1}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("pairs mismatch: got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestExactPairs_ConfirmsEquality(t *testing.T) {
+	nan := math.NaN()
+	a := makeDataset("Sample", []string{"Value"}, []any{nan})
+	b := makeDataset("Sample", []string{"Value"}, []any{nan})
+
+	got := exactPairs(a, b, newMatcher(a.columns, options{}))
+	if got[0] != -1 {
+		t.Fatalf("expected NaN rows not to be paired as identical, got %v", got)
+	}
+}
+
+func TestExactPairs_NonScalarValues(t *testing.T) {
+	a := makeDataset("Sample", []string{"Tags", "Attrs"},
+		[]any{[]string{"a"}, map[string]int{"x": 1}},
+		[]any{[]string{"b"}, map[string]int{"x": 1}},
+	)
+	b := makeDataset("Sample", []string{"Tags", "Attrs"},
+		[]any{[]string{"b"}, map[string]int{"x": 1}},
+		[]any{[]string{"a"}, map[string]int{"x": 1}},
+	)
+
+	// Rows that hash alike are told apart by the confirming comparison.
+	got := exactPairs(a, b, newMatcher(a.columns, options{}))
+	if got[0] != 1 || got[1] != 0 {
+		t.Fatalf("pairs mismatch: got %v, want [1 0]", got)
+	}
+}
+
+func TestExactPairs_NonExactColumns(t *testing.T) {
+	a := makeDataset("Order", []string{"ID", "Total", "Note"},
+		[]any{"a", 10.0, "x"},
+		[]any{"b", 20.0, "y"},
+		[]any{"c", math.NaN(), "z"},
+	)
+	b := makeDataset("Order", []string{"ID", "Total", "Note"},
+		[]any{"c", math.NaN(), "Z"},
+		[]any{"b", 20.0000001, "Y"},
+		[]any{"a", 10.0000001, "X"},
+	)
+	opts := options{tolerance: tolerance{abs: 1e-6}, nanEqual: true, columnComparers: map[string]typedComparer{
+		"Note": {typ: reflect.TypeFor[string](), equal: func(x, y any) bool { return strings.EqualFold(x.(string), y.(string)) }},
+	}}
+
+	// Rows equal only under the tolerance, EquateNaN and the comparer
+	// still pair through the hash buckets.
+	got := exactPairs(a, b, newMatcher(a.columns, opts))
+	if want := []int{2, 1, 0}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("pairs mismatch: got %v, want %v", got, want)
+	}
+}
+
+func TestExactPairs_ByValue(t *testing.T) {
+	a := makeDataset("Row", []string{"ID", "Count"}, []any{1, 2}, []any{2, -0.0})
+	b := makeDataset("Row", []string{"ID", "Count"}, []any{2.0, uint(0)}, []any{int64(1), 2.0})
+
+	m := newMatcher(a.columns, options{})
+	m.byValue = true
+	if got, want := exactPairs(a, b, m), []int{1, 0}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("pairs mismatch: got %v, want %v", got, want)
+	}
+}
+
+func TestAssert_IgnoreOrderCyclicValues(t *testing.T) {
+	type Record struct {
+		ID   int
+		Data map[string]any
+	}
+	cyclic := func() map[string]any {
+		m := map[string]any{"name": "loop"}
+		m["self"] = m
+		return m
+	}
+	a := []Record{{ID: 1, Data: cyclic()}, {ID: 2, Data: cyclic()}}
+	b := []Record{{ID: 2, Data: cyclic()}, {ID: 1, Data: cyclic()}}
+
+	r := &fakeReporter{}
+	if !Assert(r, a, b, IgnoreOrder) {
+		t.Fatalf("expected equal lists of cyclic values to match, got %q", r.errors)
+	}
+}
+
+func TestCompareUnordered_ExactThenFuzzy(t *testing.T) {
+	a := makePersonDataset(
+		[]any{"Bobby", 25},
+		[]any{"Alice", 30},
+		[]any{"Bob", 25},
+	)
+	b := makePersonDataset(
+		[]any{"Bob", 25},
+		[]any{"Alice", 31},
+		[]any{"Bobbie", 25},
+	)
+
+	got := compare(a, b, options{ignoreOrder: true})
+	if got.equal {
+		t.Fatal("expected equal=false")
+	}
+
+	// Bob pairs exactly even though Bobby comes first and would take it greedily.
+	if got.diffs[2].status != rowMatch {
+		t.Fatalf("expected exact match for Bob, got %v", got.diffs[2].status)
+	}
+	if got.diffs[0].status != rowMismatch || got.diffs[0].valuesB[0] != "Bobbie" {
+		t.Fatalf("expected Bobby to pair with Bobbie, got %#v", got.diffs[0])
+	}
+}
+
+func makeLargePersonDatasets(n, mismatches int) (dataset, dataset) {
+	rng := rand.New(rand.NewSource(int64(n)))
+	rowsA := make([][]any, n)
+	for i := range rowsA {
+		rowsA[i] = []any{fmt.Sprintf("person-%d", i), i % 90}
+	}
+
+	rowsB := make([][]any, n)
+	for i, k := range rng.Perm(n) {
+		rowsB[i] = append([]any(nil), rowsA[k]...)
+		if k < mismatches {
+			rowsB[i][1] = -1
+		}
+	}
+
+	return makePersonDataset(rowsA...), makePersonDataset(rowsB...)
+}
+
+func BenchmarkCompareUnordered(b *testing.B) {
+	for _, n := range []int{10_000, 100_000} {
+		a, bb := makeLargePersonDatasets(n, 10)
+		b.Run(fmt.Sprintf("rows=%d", n), func(b *testing.B) {
+			for b.Loop() {
+				compare(a, bb, options{ignoreOrder: true})
+			}
+		})
+	}
+}
+
+// BenchmarkGreedyPairs measures best-fit matching without the hash fast
+// path, as a baseline for BenchmarkCompareUnordered.
+func BenchmarkGreedyPairs(b *testing.B) {
+	a, bb := makeLargePersonDatasets(10_000, 10)
+	b.Run("rows=10000", func(b *testing.B) {
+		for b.Loop() {
+			greedyPairs(a, bb, newMatcher(a.columns, options{}))
+		}
+	})
+}
+
+// BenchmarkCompareUnordered_Tolerance measures unordered matching of rows
+// that are equal only within a float tolerance.
+func BenchmarkCompareUnordered_Tolerance(b *testing.B) {
+	const n = 50_000
+	rowsA := make([][]any, n)
+	rowsB := make([][]any, n)
+	for i := range rowsA {
+		total := float64(i) * 1.1
+		rowsA[i] = []any{fmt.Sprintf("order-%d", i), total}
+		rowsB[n-1-i] = []any{fmt.Sprintf("order-%d", i), total + 1e-12}
+	}
+	a := makeDataset("Order", []string{"ID", "Total"}, rowsA...)
+	bb := makeDataset("Order", []string{"ID", "Total"}, rowsB...)
+
+	b.Run(fmt.Sprintf("rows=%d", n), func(b *testing.B) {
+		for b.Loop() {
+			if compare(a, bb, options{ignoreOrder: true, tolerance: tolerance{abs: 1e-9}}).equal != true {
+				b.Fatal("expected rows within tolerance to be equal")
+			}
+		}
+	})
+}
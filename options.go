@@ -0,0 +1,559 @@
+package datadiff
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// Option configures a comparison. Options are passed to [Assert] and
+// [Diff] alongside, or instead of, [Flag] values; every flag has an
+// equivalent With constructor such as [WithIgnoreOrder].
+type Option func(*options) error
+
+// options holds the comparison settings parsed from flags and options.
+type options struct {
+	ignoreOrder     bool
+	ignoreLengths   bool
+	optimalMatching bool
+
+	// keyColumns, when non-empty, switches to keyed matching: rows are
+	// joined on the values of these columns.
+	keyColumns []string
+
+	onlyColumns   []string // if non-empty, the columns to keep, in order
+	ignoreColumns []string // columns to drop
+
+	tolerance        tolerance            // default for float columns
+	columnTolerances map[string]tolerance // per-column overrides
+	nanEqual         bool
+
+	comparers       []typedComparer          // per-type, in registration order
+	columnComparers map[string]typedComparer // per-column overrides
+
+	maxDepth    int  // nesting levels of structs to flatten
+	maxDepthSet bool // false means flatten without limit
+
+	// crossType allows lists of different element types, compared on the
+	// columns they share.
+	crossType bool
+
+	// keyFunc, when set, joins rows on a key computed from each element
+	// instead of on key columns. It takes elements of type keyType.
+	keyFunc func(elem any) any
+	keyType reflect.Type
+
+	// rowEqual, when set, decides whether two elements are equal in place
+	// of the column-by-column comparison.
+	rowEqual func(a, b any) bool
+
+	timeLayout    string           // layout for time.Time cells
+	bytesEncoding BytesEncoding    // encoding for []byte cells
+	formatters    []typedFormatter // per-type cell formatters
+	color         ColorMode        // whether to use ANSI colors
+
+	maxCellWidth    int  // widest cell before elision; 0 means no limit
+	maxCellWidthSet bool // false means defaultMaxCellWidth
+
+	showAllRows bool // list matching rows without collapsing them
+	context     int  // matching rows shown around each difference
+	contextSet  bool // false means defaultContext
+}
+
+// defaultMaxCellWidth is the widest a table cell gets, in display
+// columns, unless set with [WithMaxCellWidth].
+const defaultMaxCellWidth = 40
+
+// cellWidth returns the configured maximum cell width.
+func (o *options) cellWidth() int {
+	if !o.maxCellWidthSet {
+		return defaultMaxCellWidth
+	}
+	return o.maxCellWidth
+}
+
+// defaultContext is the number of matching rows shown before and after
+// each difference, unless set with [WithContext].
+const defaultContext = 3
+
+// contextRows returns the number of matching rows shown around each
+// difference.
+func (o *options) contextRows() int {
+	if !o.contextSet {
+		return defaultContext
+	}
+	return o.context
+}
+
+// needsElements reports whether rows must keep their original elements.
+func (o *options) needsElements() bool {
+	return o.keyFunc != nil || o.rowEqual != nil
+}
+
+// typedComparer is a user-supplied equality function for values of typ.
+type typedComparer struct {
+	typ   reflect.Type
+	equal func(a, b any) bool
+}
+
+// WithIgnoreOrder is the [Option] form of [IgnoreOrder].
+func WithIgnoreOrder() Option {
+	return flagOption(IgnoreOrder)
+}
+
+// WithIgnoreLengths is the [Option] form of [IgnoreLengths].
+func WithIgnoreLengths() Option {
+	return flagOption(IgnoreLengths)
+}
+
+// WithOptimalMatching is the [Option] form of [OptimalMatching].
+func WithOptimalMatching() Option {
+	return flagOption(OptimalMatching)
+}
+
+// WithEquateNaN is the [Option] form of [EquateNaN].
+func WithEquateNaN() Option {
+	return flagOption(EquateNaN)
+}
+
+// WithCrossType is the [Option] form of [CrossType].
+func WithCrossType() Option {
+	return flagOption(CrossType)
+}
+
+// WithShowAllRows is the [Option] form of [ShowAllRows].
+func WithShowAllRows() Option {
+	return flagOption(ShowAllRows)
+}
+
+func flagOption(flag Flag) Option {
+	return func(o *options) error {
+		return o.applyFlag(flag)
+	}
+}
+
+// WithKeys is the With-style name for [KeyColumns].
+func WithKeys(names ...string) Option {
+	return KeyColumns(names...)
+}
+
+// KeyColumns matches rows by the values of the named columns, like a
+// primary key join, instead of by position or similarity. Rows whose key
+// appears in only one list, or more than once in a list, are reported as
+// their own diff categories.
+//
+// Key columns can also be declared on the struct with a `datadiff:"key"`
+// tag; KeyColumns takes precedence over tags.
+func KeyColumns(names ...string) Option {
+	return func(o *options) error {
+		if len(names) == 0 {
+			return fmt.Errorf("datadiff: KeyColumns requires at least one column name")
+		}
+		o.keyColumns = append(o.keyColumns, names...)
+		return nil
+	}
+}
+
+// WithKeyFunc matches rows by the key fn computes from each element, like
+// [KeyColumns] but for row identities that are not a plain set of
+// columns, such as func(p Person) string { return strings.ToLower(p.Email) }.
+// Rows are labelled by their key value, such as "key=alice@example.com",
+// in the diff output. The elements of both lists must be assignable to T.
+func WithKeyFunc[T any, K comparable](fn func(T) K) Option {
+	return func(o *options) error {
+		if fn == nil {
+			return fmt.Errorf("datadiff: WithKeyFunc: nil function")
+		}
+		o.keyFunc = func(elem any) any {
+			return fn(elem.(T))
+		}
+		o.keyType = reflect.TypeFor[T]()
+		return nil
+	}
+}
+
+// OnlyColumns restricts the comparison and the diff table to the named
+// columns, shown in the given order.
+func OnlyColumns(names ...string) Option {
+	return func(o *options) error {
+		if len(names) == 0 {
+			return fmt.Errorf("datadiff: OnlyColumns requires at least one column name")
+		}
+		o.onlyColumns = append(o.onlyColumns, names...)
+		return nil
+	}
+}
+
+// IgnoreColumns excludes the named columns from the comparison and the
+// diff table, e.g. generated IDs or timestamps.
+func IgnoreColumns(names ...string) Option {
+	return func(o *options) error {
+		o.ignoreColumns = append(o.ignoreColumns, names...)
+		return nil
+	}
+}
+
+// WithTolerance treats float values as equal when they differ by at most
+// abs, or by at most rel times the larger magnitude. Pass 0 to disable
+// either bound. It applies to every float32 and float64 column that has
+// no [WithColumnTolerance] of its own.
+func WithTolerance(abs, rel float64) Option {
+	return func(o *options) error {
+		tol, err := newTolerance(abs, rel)
+		if err != nil {
+			return err
+		}
+		o.tolerance = tol
+		return nil
+	}
+}
+
+// WithColumnTolerance is like [WithTolerance] but applies only to the
+// named float column, overriding any global tolerance.
+func WithColumnTolerance(column string, abs, rel float64) Option {
+	return func(o *options) error {
+		tol, err := newTolerance(abs, rel)
+		if err != nil {
+			return err
+		}
+		if o.columnTolerances == nil {
+			o.columnTolerances = make(map[string]tolerance)
+		}
+		o.columnTolerances[column] = tol
+		return nil
+	}
+}
+
+// WithComparer registers fn as the equality function for values of type
+// T, such as func(a, b *big.Int) bool { return a.Cmp(b) == 0 }. If T is
+// an interface type, fn applies to any values whose types implement it.
+// Comparers take precedence over Equal methods and tolerances; the most
+// recently registered comparer for a type wins.
+func WithComparer[T any](fn func(a, b T) bool) Option {
+	return func(o *options) error {
+		if fn == nil {
+			return fmt.Errorf("datadiff: WithComparer: nil function")
+		}
+		o.comparers = append(o.comparers, newTypedComparer(fn))
+		return nil
+	}
+}
+
+// WithColumnComparer registers fn as the equality function for the named
+// column, such as WithColumnComparer("Email", strings.EqualFold). The
+// column's type must be assignable to T.
+func WithColumnComparer[T any](column string, fn func(a, b T) bool) Option {
+	return func(o *options) error {
+		if fn == nil {
+			return fmt.Errorf("datadiff: WithColumnComparer %q: nil function", column)
+		}
+		if o.columnComparers == nil {
+			o.columnComparers = make(map[string]typedComparer)
+		}
+		o.columnComparers[column] = newTypedComparer(fn)
+		return nil
+	}
+}
+
+func newTypedComparer[T any](fn func(a, b T) bool) typedComparer {
+	return typedComparer{
+		typ: reflect.TypeFor[T](),
+		equal: func(a, b any) bool {
+			ta, okA := a.(T)
+			tb, okB := b.(T)
+			if !okA || !okB {
+				return reflect.DeepEqual(a, b)
+			}
+			return fn(ta, tb)
+		},
+	}
+}
+
+func newTolerance(abs, rel float64) (tolerance, error) {
+	if abs < 0 || rel < 0 || math.IsNaN(abs) || math.IsNaN(rel) {
+		return tolerance{}, fmt.Errorf("datadiff: tolerance must be non-negative, got abs=%v rel=%v", abs, rel)
+	}
+	return tolerance{abs: abs, rel: rel}, nil
+}
+
+// WithMaxDepth limits how many levels of nested struct fields are
+// flattened into dotted columns such as "Address.Zip". Structs below the
+// limit are compared and shown as single values. WithMaxDepth(0) disables
+// flattening. By default nested structs are flattened at any depth.
+func WithMaxDepth(n int) Option {
+	return func(o *options) error {
+		if n < 0 {
+			return fmt.Errorf("datadiff: WithMaxDepth: depth must be non-negative, got %d", n)
+		}
+		o.maxDepth = n
+		o.maxDepthSet = true
+		return nil
+	}
+}
+
+// WithTimeFormat sets the layout, as for [time.Time.Format], used to show
+// time.Time cells. The default is [time.RFC3339Nano].
+func WithTimeFormat(layout string) Option {
+	return func(o *options) error {
+		if layout == "" {
+			return fmt.Errorf("datadiff: WithTimeFormat: empty layout")
+		}
+		o.timeLayout = layout
+		return nil
+	}
+}
+
+// WithBytesEncoding sets how []byte cells are shown. The default is
+// [BytesHex].
+func WithBytesEncoding(enc BytesEncoding) Option {
+	return func(o *options) error {
+		if enc != BytesHex && enc != BytesBase64 {
+			return fmt.Errorf("datadiff: WithBytesEncoding: unknown encoding %d", enc)
+		}
+		o.bytesEncoding = enc
+		return nil
+	}
+}
+
+// WithFormatter registers fn to show cells holding values of type T in
+// the diff table, such as func(d decimal.Decimal) string { return
+// d.StringFixed(2) }. If T is an interface type, fn applies to any values
+// whose types implement it. Formatters only affect display; the most
+// recently registered formatter for a type wins.
+func WithFormatter[T any](fn func(T) string) Option {
+	return func(o *options) error {
+		if fn == nil {
+			return fmt.Errorf("datadiff: WithFormatter: nil function")
+		}
+		o.formatters = append(o.formatters, typedFormatter{
+			typ: reflect.TypeFor[T](),
+			format: func(v any) string {
+				return fn(v.(T))
+			},
+		})
+		return nil
+	}
+}
+
+// WithMaxCellWidth limits table cells to n display columns. Longer values
+// are elided with "…"; long mismatched values show only the region where
+// they differ, as in "…New Y[o→a]rk…". WithMaxCellWidth(0) disables the
+// limit. The default is 40.
+func WithMaxCellWidth(n int) Option {
+	return func(o *options) error {
+		if n < 0 || n > 0 && n < 8 {
+			return fmt.Errorf("datadiff: WithMaxCellWidth: width must be 0 or at least 8, got %d", n)
+		}
+		o.maxCellWidth = n
+		o.maxCellWidthSet = true
+		return nil
+	}
+}
+
+// WithContext shows n matching rows before and after each mismatched or
+// extra row, like diff -U; longer runs of matching rows are collapsed into
+// a single "… 4,812 matching rows …" line. The default is 3. Use
+// [ShowAllRows] to list every row.
+func WithContext(n int) Option {
+	return func(o *options) error {
+		if n < 0 {
+			return fmt.Errorf("datadiff: WithContext: negative row count %d", n)
+		}
+		o.context = n
+		o.contextSet = true
+		return nil
+	}
+}
+
+// parseOptions applies flags and options in order and validates the result.
+func parseOptions(flags []any) (options, error) {
+	var opts options
+	for _, f := range flags {
+		switch flag := f.(type) {
+		case Flag:
+			if err := opts.applyFlag(flag); err != nil {
+				return options{}, err
+			}
+		case Option:
+			if flag == nil {
+				return options{}, fmt.Errorf("datadiff: nil option")
+			}
+			if err := flag(&opts); err != nil {
+				return options{}, err
+			}
+		default:
+			return options{}, fmt.Errorf("datadiff: unknown flag type %T (expected datadiff.Flag or datadiff.Option)", f)
+		}
+	}
+
+	if err := opts.validate(); err != nil {
+		return options{}, err
+	}
+
+	return opts, nil
+}
+
+func (o *options) applyFlag(flag Flag) error {
+	switch flag {
+	case IgnoreOrder:
+		o.ignoreOrder = true
+	case IgnoreLengths:
+		o.ignoreLengths = true
+	case OptimalMatching:
+		o.optimalMatching = true
+	case EquateNaN:
+		o.nanEqual = true
+	case CrossType:
+		o.crossType = true
+	case ShowAllRows:
+		o.showAllRows = true
+	default:
+		return fmt.Errorf("datadiff: unknown flag value: %d", flag)
+	}
+	return nil
+}
+
+// validate reports combinations of options that contradict each other.
+func (o *options) validate() error {
+	if o.optimalMatching && !o.ignoreOrder {
+		return fmt.Errorf("datadiff: conflicting options: OptimalMatching requires IgnoreOrder")
+	}
+	if len(o.onlyColumns) > 0 && len(o.ignoreColumns) > 0 {
+		return fmt.Errorf("datadiff: conflicting options: OnlyColumns and IgnoreColumns cannot be combined")
+	}
+	if o.showAllRows && o.contextSet {
+		return fmt.Errorf("datadiff: conflicting options: ShowAllRows and WithContext cannot be combined")
+	}
+
+	if o.keyFunc != nil && len(o.keyColumns) > 0 {
+		return fmt.Errorf("datadiff: conflicting options: a key function and KeyColumns cannot be combined")
+	}
+	if o.keyFunc != nil && o.optimalMatching {
+		return fmt.Errorf("datadiff: conflicting options: OptimalMatching has no effect when rows are matched by a key function")
+	}
+
+	for i, name := range o.keyColumns {
+		if columnIndex(o.keyColumns[:i], name) >= 0 {
+			return fmt.Errorf("datadiff: conflicting options: key column %q listed more than once", name)
+		}
+	}
+	for i, name := range o.onlyColumns {
+		if columnIndex(o.onlyColumns[:i], name) >= 0 {
+			return fmt.Errorf("datadiff: conflicting options: column %q listed more than once in OnlyColumns", name)
+		}
+	}
+	for column := range o.columnTolerances {
+		if _, ok := o.columnComparers[column]; ok {
+			return fmt.Errorf("datadiff: conflicting options: column %q has both a tolerance and a comparer", column)
+		}
+	}
+
+	return nil
+}
+
+// resolveKeyColumns falls back to key columns declared with struct tags
+// and checks that every key column exists and that keyed matching does
+// not conflict with other options.
+func resolveKeyColumns(opts *options, ds dataset) error {
+	if opts.keyFunc != nil {
+		if !ds.elemType.AssignableTo(opts.keyType) {
+			return fmt.Errorf("datadiff: key function takes %s, but elements are %s", opts.keyType, ds.elemType)
+		}
+		return nil
+	}
+
+	if len(opts.keyColumns) == 0 {
+		opts.keyColumns = ds.keyColumns
+	}
+
+	if opts.optimalMatching && len(opts.keyColumns) > 0 {
+		return fmt.Errorf("datadiff: conflicting options: OptimalMatching has no effect when rows are matched by key columns %q", opts.keyColumns)
+	}
+
+	for _, name := range opts.keyColumns {
+		if columnIndex(ds.columns, name) < 0 {
+			return fmt.Errorf("datadiff: key column %q not found in %s", name, ds.typeName)
+		}
+	}
+
+	return nil
+}
+
+// selectColumns applies OnlyColumns and IgnoreColumns to ds. Every named
+// column must be among known, the columns of both lists when their
+// schemas differ; named columns that ds lacks are skipped. Key columns
+// cannot be excluded.
+func selectColumns(ds dataset, known []string, opts options) (dataset, error) {
+	if len(opts.onlyColumns) == 0 && len(opts.ignoreColumns) == 0 {
+		return ds, nil
+	}
+
+	for _, names := range [][]string{opts.onlyColumns, opts.ignoreColumns} {
+		for _, name := range names {
+			if columnIndex(known, name) < 0 {
+				return dataset{}, fmt.Errorf("datadiff: column %q not found in %s", name, ds.typeName)
+			}
+		}
+	}
+
+	selected := ds.columns
+	if len(opts.onlyColumns) > 0 {
+		selected = opts.onlyColumns
+	}
+
+	indexes := make([]int, 0, len(selected))
+	for _, name := range selected {
+		index := columnIndex(ds.columns, name)
+		if index < 0 || columnIndex(opts.ignoreColumns, name) >= 0 {
+			continue
+		}
+		indexes = append(indexes, index)
+	}
+
+	projected := ds.project(indexes)
+	for _, name := range opts.keyColumns {
+		if columnIndex(projected.columns, name) < 0 {
+			return dataset{}, fmt.Errorf("datadiff: key column %q is excluded from the comparison", name)
+		}
+	}
+
+	return projected, nil
+}
+
+// validateColumnOptions checks that columns named by per-column options
+// exist in ds and have a suitable type.
+func validateColumnOptions(ds dataset, opts options) error {
+	for column := range opts.columnTolerances {
+		i := columnIndex(ds.columns, column)
+		if i < 0 {
+			return fmt.Errorf("datadiff: tolerance column %q not found in %s", column, ds.typeName)
+		}
+		if i < len(ds.types) {
+			if kind := ds.types[i].Kind(); kind != reflect.Float32 && kind != reflect.Float64 {
+				return fmt.Errorf("datadiff: tolerance column %q has non-float type %s", column, ds.types[i])
+			}
+		}
+	}
+
+	for column, comparer := range opts.columnComparers {
+		i := columnIndex(ds.columns, column)
+		if i < 0 {
+			return fmt.Errorf("datadiff: comparer column %q not found in %s", column, ds.typeName)
+		}
+		if i < len(ds.types) && !ds.types[i].AssignableTo(comparer.typ) {
+			return fmt.Errorf("datadiff: comparer column %q has type %s, not assignable to %s", column, ds.types[i], comparer.typ)
+		}
+	}
+
+	return nil
+}
+
+// columnIndex returns the position of name in columns, or -1.
+func columnIndex(columns []string, name string) int {
+	for i, column := range columns {
+		if column == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,289 @@
+package datadiff
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseOptions_FlagsAndOptions(t *testing.T) {
+	got, err := parseOptions([]any{IgnoreLengths, KeyColumns("ID"), KeyColumns("Region")})
+	if err != nil {
+		t.Fatalf("parseOptions returned unexpected error: %v", err)
+	}
+
+	if !got.ignoreLengths || got.ignoreOrder {
+		t.Fatalf("flag mismatch: got %#v", got)
+	}
+
+	wantKeys := []string{"ID", "Region"}
+	if !reflect.DeepEqual(got.keyColumns, wantKeys) {
+		t.Fatalf("key columns mismatch: got %#v, want %#v", got.keyColumns, wantKeys)
+	}
+}
+
+func TestParseOptions_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   []any
+		wantErr string
+	}{
+		{name: "unknown type", flags: []any{42}, wantErr: "unknown flag type int"},
+		{name: "unknown flag", flags: []any{Flag(0)}, wantErr: "unknown flag value: 0"},
+		{name: "nil option", flags: []any{Option(nil)}, wantErr: "nil option"},
+		{name: "empty key columns", flags: []any{KeyColumns()}, wantErr: "KeyColumns requires at least one column name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseOptions(tt.flags)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error mismatch: got %q, want substring %q", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolveKeyColumns(t *testing.T) {
+	ds := makeDataset("User", []string{"ID", "Name"})
+	ds.keyColumns = []string{"ID"}
+
+	var opts options
+	if err := resolveKeyColumns(&opts, ds); err != nil {
+		t.Fatalf("resolveKeyColumns returned unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(opts.keyColumns, []string{"ID"}) {
+		t.Fatalf("expected tag key columns, got %#v", opts.keyColumns)
+	}
+
+	opts = options{keyColumns: []string{"Name"}}
+	if err := resolveKeyColumns(&opts, ds); err != nil {
+		t.Fatalf("resolveKeyColumns returned unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(opts.keyColumns, []string{"Name"}) {
+		t.Fatalf("expected option key columns to take precedence, got %#v", opts.keyColumns)
+	}
+
+	opts = options{keyColumns: []string{"Email"}}
+	err := resolveKeyColumns(&opts, ds)
+	if err == nil || !strings.Contains(err.Error(), `key column "Email" not found in User`) {
+		t.Fatalf("expected unknown key column error, got %v", err)
+	}
+}
+
+func TestSelectColumns(t *testing.T) {
+	ds := makeDataset("User", []string{"ID", "Name", "Total", "UpdatedAt"},
+		[]any{1, "Alice", 10, "t1"},
+	)
+
+	tests := []struct {
+		name        string
+		opts        options
+		wantColumns []string
+		wantValues  []any
+	}{
+		{name: "none", opts: options{}, wantColumns: []string{"ID", "Name", "Total", "UpdatedAt"}, wantValues: []any{1, "Alice", 10, "t1"}},
+		{name: "ignore", opts: options{ignoreColumns: []string{"UpdatedAt", "ID"}}, wantColumns: []string{"Name", "Total"}, wantValues: []any{"Alice", 10}},
+		{name: "only", opts: options{onlyColumns: []string{"Total", "Name"}}, wantColumns: []string{"Total", "Name"}, wantValues: []any{10, "Alice"}},
+		{name: "only with key", opts: options{onlyColumns: []string{"ID", "Total"}, keyColumns: []string{"ID"}}, wantColumns: []string{"ID", "Total"}, wantValues: []any{1, 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectColumns(ds, ds.columns, tt.opts)
+			if err != nil {
+				t.Fatalf("selectColumns returned unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got.columns, tt.wantColumns) {
+				t.Fatalf("columns mismatch: got %#v, want %#v", got.columns, tt.wantColumns)
+			}
+			if !reflect.DeepEqual(got.rows[0].values, tt.wantValues) {
+				t.Fatalf("row mismatch: got %#v, want %#v", got.rows[0].values, tt.wantValues)
+			}
+		})
+	}
+}
+
+func TestSelectColumns_Errors(t *testing.T) {
+	ds := makeDataset("User", []string{"ID", "Name"})
+
+	tests := []struct {
+		name    string
+		opts    options
+		wantErr string
+	}{
+		{name: "unknown ignored", opts: options{ignoreColumns: []string{"Email"}}, wantErr: `column "Email" not found in User`},
+		{name: "unknown only", opts: options{onlyColumns: []string{"Email"}}, wantErr: `column "Email" not found in User`},
+		{name: "key ignored", opts: options{ignoreColumns: []string{"ID"}, keyColumns: []string{"ID"}}, wantErr: `key column "ID" is excluded`},
+		{name: "key not selected", opts: options{onlyColumns: []string{"Name"}, keyColumns: []string{"ID"}}, wantErr: `key column "ID" is excluded`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := selectColumns(ds, ds.columns, tt.opts)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error mismatch: got %q, want substring %q", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWithTolerance_Invalid(t *testing.T) {
+	for _, option := range []Option{WithTolerance(-1, 0), WithColumnTolerance("Total", 0, math.NaN())} {
+		_, err := parseOptions([]any{option})
+		if err == nil || !strings.Contains(err.Error(), "tolerance must be non-negative") {
+			t.Fatalf("expected invalid tolerance error, got %v", err)
+		}
+	}
+}
+
+func TestValidateColumnOptions(t *testing.T) {
+	ds := makeDataset("Order", []string{"ID", "Total"})
+	ds.types = []reflect.Type{reflect.TypeFor[int](), reflect.TypeFor[float64]()}
+
+	if err := validateColumnOptions(ds, options{columnTolerances: map[string]tolerance{"Total": {abs: 1}}}); err != nil {
+		t.Fatalf("validateColumnOptions returned unexpected error: %v", err)
+	}
+
+	err := validateColumnOptions(ds, options{columnTolerances: map[string]tolerance{"Amount": {abs: 1}}})
+	if err == nil || !strings.Contains(err.Error(), `tolerance column "Amount" not found in Order`) {
+		t.Fatalf("expected unknown column error, got %v", err)
+	}
+
+	err = validateColumnOptions(ds, options{columnTolerances: map[string]tolerance{"ID": {abs: 1}}})
+	if err == nil || !strings.Contains(err.Error(), `tolerance column "ID" has non-float type int`) {
+		t.Fatalf("expected non-float column error, got %v", err)
+	}
+}
+
+func TestValidateColumnOptions_Comparers(t *testing.T) {
+	ds := makeDataset("User", []string{"ID", "Email"})
+	ds.types = []reflect.Type{reflect.TypeFor[int](), reflect.TypeFor[string]()}
+
+	valid := options{columnComparers: map[string]typedComparer{"Email": newTypedComparer(strings.EqualFold)}}
+	if err := validateColumnOptions(ds, valid); err != nil {
+		t.Fatalf("validateColumnOptions returned unexpected error: %v", err)
+	}
+
+	err := validateColumnOptions(ds, options{columnComparers: map[string]typedComparer{"Mail": newTypedComparer(strings.EqualFold)}})
+	if err == nil || !strings.Contains(err.Error(), `comparer column "Mail" not found in User`) {
+		t.Fatalf("expected unknown column error, got %v", err)
+	}
+
+	err = validateColumnOptions(ds, options{columnComparers: map[string]typedComparer{"ID": newTypedComparer(strings.EqualFold)}})
+	if err == nil || !strings.Contains(err.Error(), `comparer column "ID" has type int, not assignable to string`) {
+		t.Fatalf("expected type error, got %v", err)
+	}
+}
+
+func TestWithComparer_Nil(t *testing.T) {
+	if _, err := parseOptions([]any{WithComparer[string](nil)}); err == nil || !strings.Contains(err.Error(), "nil function") {
+		t.Fatalf("expected nil function error, got %v", err)
+	}
+	if _, err := parseOptions([]any{WithColumnComparer[string]("Email", nil)}); err == nil || !strings.Contains(err.Error(), "nil function") {
+		t.Fatalf("expected nil function error, got %v", err)
+	}
+}
+
+func TestParseOptions_WithConstructors(t *testing.T) {
+	got, err := parseOptions([]any{WithIgnoreOrder(), WithOptimalMatching(), WithIgnoreLengths(), WithEquateNaN(), WithTolerance(0.1, 0)})
+	if err != nil {
+		t.Fatalf("parseOptions returned unexpected error: %v", err)
+	}
+
+	want := options{ignoreOrder: true, ignoreLengths: true, optimalMatching: true, nanEqual: true, tolerance: tolerance{abs: 0.1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("options mismatch: got %#v, want %#v", got, want)
+	}
+
+	got, err = parseOptions([]any{WithKeys("ID", "Region")})
+	if err != nil {
+		t.Fatalf("parseOptions returned unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got.keyColumns, []string{"ID", "Region"}) {
+		t.Fatalf("key columns mismatch: got %#v", got.keyColumns)
+	}
+}
+
+func TestParseOptions_Conflicts(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   []any
+		wantErr string
+	}{
+		{name: "optimal without ignore order", flags: []any{OptimalMatching}, wantErr: "OptimalMatching requires IgnoreOrder"},
+		{name: "only and ignore", flags: []any{OnlyColumns("A"), IgnoreColumns("B")}, wantErr: "OnlyColumns and IgnoreColumns cannot be combined"},
+		{name: "duplicate key", flags: []any{WithKeys("ID"), KeyColumns("ID")}, wantErr: `key column "ID" listed more than once`},
+		{name: "duplicate only column", flags: []any{OnlyColumns("A", "A")}, wantErr: `column "A" listed more than once in OnlyColumns`},
+		{name: "tolerance and comparer", flags: []any{WithColumnTolerance("Total", 1, 0), WithColumnComparer("Total", func(a, b float64) bool { return true })}, wantErr: `column "Total" has both a tolerance and a comparer`},
+		{name: "all rows and context", flags: []any{ShowAllRows, WithContext(2)}, wantErr: "ShowAllRows and WithContext cannot be combined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseOptions(tt.flags)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), "datadiff: conflicting options: "+tt.wantErr) {
+				t.Fatalf("error mismatch: got %q, want substring %q", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolveKeyColumns_OptimalMatchingConflict(t *testing.T) {
+	ds := makeDataset("User", []string{"ID", "Name"})
+	ds.keyColumns = []string{"ID"}
+
+	opts := options{ignoreOrder: true, optimalMatching: true}
+	err := resolveKeyColumns(&opts, ds)
+	if err == nil || !strings.Contains(err.Error(), "OptimalMatching has no effect when rows are matched by key columns") {
+		t.Fatalf("expected conflict error, got %v", err)
+	}
+}
+
+func TestWithMaxDepth(t *testing.T) {
+	got, err := parseOptions([]any{WithMaxDepth(2)})
+	if err != nil {
+		t.Fatalf("parseOptions returned unexpected error: %v", err)
+	}
+	if got.maxDepth != 2 || !got.maxDepthSet {
+		t.Fatalf("max depth mismatch: got %#v", got)
+	}
+
+	if _, err := parseOptions([]any{WithMaxDepth(-1)}); err == nil || !strings.Contains(err.Error(), "depth must be non-negative") {
+		t.Fatalf("expected negative depth error, got %v", err)
+	}
+}
+
+func TestFormatOptions_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		opt     Option
+		wantErr string
+	}{
+		{name: "empty time layout", opt: WithTimeFormat(""), wantErr: "WithTimeFormat: empty layout"},
+		{name: "unknown bytes encoding", opt: WithBytesEncoding(BytesEncoding(9)), wantErr: "WithBytesEncoding: unknown encoding 9"},
+		{name: "nil formatter", opt: WithFormatter[string](nil), wantErr: "WithFormatter: nil function"},
+		{name: "negative cell width", opt: WithMaxCellWidth(-1), wantErr: "WithMaxCellWidth: width must be 0 or at least 8, got -1"},
+		{name: "narrow cell width", opt: WithMaxCellWidth(5), wantErr: "WithMaxCellWidth: width must be 0 or at least 8, got 5"},
+		{name: "negative context", opt: WithContext(-1), wantErr: "WithContext: negative row count -1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseOptions([]any{tt.opt})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error mismatch: got %v, want substring %q", err, tt.wantErr)
+			}
+		})
+	}
+}
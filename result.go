@@ -0,0 +1,180 @@
+package datadiff
+
+import (
+	"reflect"
+	"slices"
+)
+
+// RowStatus classifies the outcome of comparing one row.
+type RowStatus int
+
+const (
+	// RowMatch means the row is present in both lists with equal values.
+	RowMatch RowStatus = iota
+
+	// RowMismatch means the row is present in both lists but at least one
+	// field differs. See [RowDiff.Mismatch] for the affected fields.
+	RowMismatch
+
+	// RowExtra means the row is present in only one of the lists.
+	RowExtra
+
+	// RowMissingKey means the row's key, when matching by [KeyColumns],
+	// is present in only one of the lists.
+	RowMissingKey
+
+	// RowDuplicateKey means the row's key, when matching by [KeyColumns],
+	// occurs more than once in its list. Such rows are not compared.
+	RowDuplicateKey
+)
+
+// String returns a lower-case name for the status.
+func (s RowStatus) String() string {
+	switch s {
+	case RowMatch:
+		return "match"
+	case RowMismatch:
+		return "mismatch"
+	case RowExtra:
+		return "extra"
+	case RowMissingKey:
+		return "missing key"
+	case RowDuplicateKey:
+		return "duplicate key"
+	default:
+		return "unknown"
+	}
+}
+
+// RowDiff describes the comparison outcome for one row.
+type RowDiff struct {
+	// Index is the row index in the original list. For extra rows it is
+	// the index in whichever list the row came from.
+	Index int
+
+	// Key labels the row by its key values, such as "ID=7", when rows are
+	// matched by [KeyColumns]. It is empty otherwise.
+	Key string
+
+	Status RowStatus
+
+	// Expected holds the field values from listA, or nil if the row is
+	// missing from listA.
+	Expected []any
+
+	// Actual holds the field values from listB, or nil if the row is
+	// missing from listB.
+	Actual []any
+
+	// Mismatch reports, per column, whether the values differ. It is nil
+	// unless Status is RowMismatch.
+	Mismatch []bool
+
+	// ExpectedNil and ActualNil report whether the element in listA or
+	// listB is a nil pointer. The corresponding values are then all nil.
+	ExpectedNil bool
+	ActualNil   bool
+}
+
+// SchemaDiff lists the columns that differ between the two lists, such
+// as between two versions of a struct or, with [CrossType], between
+// different element types.
+type SchemaDiff struct {
+	// Removed holds the columns present only in listA (expected).
+	Removed []string
+
+	// Added holds the columns present only in listB (actual).
+	Added []string
+
+	// Retyped holds the columns present in both lists with different
+	// static types. Their values are still compared.
+	Retyped []RetypedColumn
+}
+
+// RetypedColumn is a column whose type differs between the two lists.
+type RetypedColumn struct {
+	Name     string
+	Expected reflect.Type // type in listA
+	Actual   reflect.Type // type in listB
+}
+
+// Empty reports whether the two lists have the same columns and types.
+func (s SchemaDiff) Empty() bool {
+	return len(s.Removed) == 0 && len(s.Added) == 0 && len(s.Retyped) == 0
+}
+
+// Result is the structured outcome of [Diff].
+type Result struct {
+	result diffResult
+}
+
+// Equal reports whether the lists are equal under the flags given to [Diff].
+func (r Result) Equal() bool {
+	return r.result.equal
+}
+
+// TypeName returns the type name of the compared elements, such as
+// "Person" or "*Person".
+func (r Result) TypeName() string {
+	return r.result.typeName
+}
+
+// TypeMismatch reports whether the lists have different element types.
+// When true, rows are not compared and [Result.Rows] is empty.
+func (r Result) TypeMismatch() bool {
+	return r.result.typeMismatch
+}
+
+// OtherTypeName returns the element type name of listB when it differs
+// from [Result.TypeName], and "" otherwise.
+func (r Result) OtherTypeName() string {
+	return r.result.otherTypeName
+}
+
+// Schema returns the columns added, removed or retyped between the lists.
+// Rows are compared on the columns present in both. It is also filled in
+// when [Result.TypeMismatch] is true.
+func (r Result) Schema() SchemaDiff {
+	var schema SchemaDiff
+	for _, column := range r.result.schema.removed {
+		schema.Removed = append(schema.Removed, column.name)
+	}
+	for _, column := range r.result.schema.added {
+		schema.Added = append(schema.Added, column.name)
+	}
+	for _, column := range r.result.schema.retyped {
+		schema.Retyped = append(schema.Retyped, RetypedColumn{Name: column.name, Expected: column.typeA, Actual: column.typeB})
+	}
+	return schema
+}
+
+// Columns returns the column names in display order.
+func (r Result) Columns() []string {
+	return append([]string(nil), r.result.columns...)
+}
+
+// Rows returns the per-row comparison outcomes in display order. The
+// slices in each RowDiff are copies; changing them does not affect r.
+func (r Result) Rows() []RowDiff {
+	rows := make([]RowDiff, len(r.result.diffs))
+	for i, diff := range r.result.diffs {
+		rows[i] = RowDiff{
+			Index:    diff.index,
+			Key:      diff.key,
+			Status:   RowStatus(diff.status),
+			Expected: slices.Clone(diff.valuesA),
+			Actual:   slices.Clone(diff.valuesB),
+			Mismatch: slices.Clone(diff.mismatch),
+
+			ExpectedNil: diff.nilA,
+			ActualNil:   diff.nilB,
+		}
+	}
+	return rows
+}
+
+// String renders the result as the tabular diff printed by [Assert].
+// It returns an empty string when the lists are equal.
+func (r Result) String() string {
+	return formatDiff(r.result)
+}
@@ -0,0 +1,164 @@
+package datadiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiff_Equal(t *testing.T) {
+	a := []Person{{Name: "Alice", Age: 30}, {Name: "Bob", Age: 25}}
+	b := []Person{{Name: "Alice", Age: 30}, {Name: "Bob", Age: 25}}
+
+	got, err := Diff(a, b)
+	if err != nil {
+		t.Fatalf("Diff returned unexpected error: %v", err)
+	}
+	if !got.Equal() {
+		t.Fatal("expected Equal()=true")
+	}
+	if got.String() != "" {
+		t.Fatalf("expected empty String() for equal result, got %q", got.String())
+	}
+	if got.TypeName() != "Person" {
+		t.Fatalf("typeName mismatch: got %q, want %q", got.TypeName(), "Person")
+	}
+
+	wantColumns := []string{"Name", "Age"}
+	if !reflect.DeepEqual(got.Columns(), wantColumns) {
+		t.Fatalf("columns mismatch: got %#v, want %#v", got.Columns(), wantColumns)
+	}
+}
+
+func TestDiff_Mismatch(t *testing.T) {
+	a := []Person{{Name: "Alice", Age: 30}, {Name: "Bob", Age: 25}, {Name: "Charlie", Age: 35}}
+	b := []Person{{Name: "Alice", Age: 30}, {Name: "Bob", Age: 26}}
+
+	got, err := Diff(a, b)
+	if err != nil {
+		t.Fatalf("Diff returned unexpected error: %v", err)
+	}
+	if got.Equal() {
+		t.Fatal("expected Equal()=false")
+	}
+
+	rows := got.Rows()
+	if len(rows) != 3 {
+		t.Fatalf("row count mismatch: got %d, want %d", len(rows), 3)
+	}
+
+	wantStatuses := []RowStatus{RowMatch, RowMismatch, RowExtra}
+	for i, want := range wantStatuses {
+		if rows[i].Status != want {
+			t.Fatalf("row %d status mismatch: got %v, want %v", i, rows[i].Status, want)
+		}
+	}
+
+	if !reflect.DeepEqual(rows[1].Mismatch, []bool{false, true}) {
+		t.Fatalf("mismatch flags mismatch: got %#v", rows[1].Mismatch)
+	}
+	if !reflect.DeepEqual(rows[1].Expected, []any{"Bob", 25}) || !reflect.DeepEqual(rows[1].Actual, []any{"Bob", 26}) {
+		t.Fatalf("row values mismatch: expected=%#v actual=%#v", rows[1].Expected, rows[1].Actual)
+	}
+	if rows[2].Expected == nil || rows[2].Actual != nil {
+		t.Fatalf("expected extra-in-expected row, got expected=%#v actual=%#v", rows[2].Expected, rows[2].Actual)
+	}
+
+	if !strings.Contains(stripANSI(got.String()), "datadiff: []Person are not equal") {
+		t.Fatalf("expected String() to render the diff table, got %q", got.String())
+	}
+}
+
+func TestDiff_Flags(t *testing.T) {
+	a := []Person{{Name: "Alice", Age: 30}, {Name: "Bob", Age: 25}}
+	b := []Person{{Name: "Bob", Age: 25}, {Name: "Alice", Age: 30}}
+
+	got, err := Diff(a, b, IgnoreOrder)
+	if err != nil {
+		t.Fatalf("Diff returned unexpected error: %v", err)
+	}
+	if !got.Equal() {
+		t.Fatalf("expected Equal()=true with IgnoreOrder, got diff:\n%s", got)
+	}
+}
+
+func TestDiff_TypeMismatch(t *testing.T) {
+	got, err := Diff([]Person{{Name: "Alice", Age: 30}}, []Employee{{Name: "Alice", Age: 30}})
+	if err != nil {
+		t.Fatalf("Diff returned unexpected error: %v", err)
+	}
+	if got.Equal() || !got.TypeMismatch() {
+		t.Fatalf("expected unequal type mismatch result, got Equal()=%v TypeMismatch()=%v", got.Equal(), got.TypeMismatch())
+	}
+	if !strings.Contains(got.String(), "datadiff: type mismatch: []Person vs []Employee") {
+		t.Fatalf("expected type mismatch message, got %q", got.String())
+	}
+}
+
+func TestDiff_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		listA   any
+		listB   any
+		flags   []any
+		wantErr string
+	}{
+		{name: "nil first", listA: nil, listB: []Person{}, wantErr: "datadiff: first argument: datadiff: input is nil"},
+		{name: "non-slice second", listA: []Person{}, listB: 42, wantErr: "datadiff: second argument: datadiff: expected slice"},
+		{name: "invalid flag", listA: []Person{}, listB: []Person{}, flags: []any{"bad"}, wantErr: "datadiff: unknown flag type string"},
+		{name: "invalid flag value", listA: []Person{}, listB: []Person{}, flags: []any{Flag(99)}, wantErr: "datadiff: unknown flag value: 99"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Diff(tt.listA, tt.listB, tt.flags...)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error mismatch: got %q, want substring %q", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRowStatus_String(t *testing.T) {
+	tests := map[RowStatus]string{
+		RowMatch:        "match",
+		RowMismatch:     "mismatch",
+		RowExtra:        "extra",
+		RowMissingKey:   "missing key",
+		RowDuplicateKey: "duplicate key",
+		RowStatus(99):   "unknown",
+	}
+
+	for status, want := range tests {
+		if got := status.String(); got != want {
+			t.Fatalf("String() mismatch for %d: got %q, want %q", int(status), got, want)
+		}
+	}
+}
+
+func TestResult_RowsAreCopies(t *testing.T) {
+	a := []Person{{Name: "Alice", Age: 30}}
+	b := []Person{{Name: "Alice", Age: 31}}
+
+	result, err := Diff(a, b, WithColor(ColorNever))
+	if err != nil {
+		t.Fatalf("Diff returned unexpected error: %v", err)
+	}
+	before := result.String()
+
+	rows := result.Rows()
+	rows[0].Mismatch[1] = false
+	rows[0].Mismatch[0] = true
+	rows[0].Expected[0] = "Mallory"
+	rows[0].Actual[1] = 99
+
+	if got := result.String(); got != before {
+		t.Fatalf("expected Rows to return copies, output changed:\n%s\nwant:\n%s", got, before)
+	}
+	if got := result.Rows()[0]; got.Mismatch[0] || !got.Mismatch[1] || got.Expected[0] != "Alice" || got.Actual[1] != 31 {
+		t.Fatalf("expected Rows to be unchanged, got %+v", got)
+	}
+}
@@ -0,0 +1,73 @@
+package datadiff
+
+import "reflect"
+
+// schemaDiff describes the columns that differ between two datasets.
+type schemaDiff struct {
+	removed []schemaColumn // columns only in listA (expected)
+	added   []schemaColumn // columns only in listB (actual)
+	retyped []schemaColumn // shared columns whose static types differ
+}
+
+// schemaColumn is a column in a schemaDiff. typeA and typeB are its static
+// types in listA and listB, or nil if unknown, such as for map[string]any
+// rows, or if the column is absent from that list.
+type schemaColumn struct {
+	name         string
+	typeA, typeB reflect.Type
+}
+
+func (s schemaDiff) empty() bool {
+	return len(s.removed) == 0 && len(s.added) == 0 && len(s.retyped) == 0
+}
+
+// alignSchemas restricts a and b to the columns they share, in the order
+// of a, and reports the columns found on only one side and the shared
+// columns whose types differ. Retyped columns are still compared, by
+// value if both types are numbers, strings or booleans.
+func alignSchemas(a, b dataset) (dataset, dataset, schemaDiff) {
+	var schema schemaDiff
+	var indexesA, indexesB []int
+	for i, column := range a.columns {
+		j := columnIndex(b.columns, column)
+		if j < 0 {
+			schema.removed = append(schema.removed, schemaColumn{name: column, typeA: a.columnType(i)})
+			continue
+		}
+		indexesA = append(indexesA, i)
+		indexesB = append(indexesB, j)
+
+		typeA, typeB := a.columnType(i), b.columnType(j)
+		if typeA != nil && typeB != nil && typeA != typeB {
+			schema.retyped = append(schema.retyped, schemaColumn{name: column, typeA: typeA, typeB: typeB})
+		}
+	}
+	for j, column := range b.columns {
+		if columnIndex(a.columns, column) < 0 {
+			schema.added = append(schema.added, schemaColumn{name: column, typeB: b.columnType(j)})
+		}
+	}
+
+	if len(schema.removed) == 0 && len(schema.added) == 0 && len(indexesB) == len(b.columns) && isIdentity(indexesB) {
+		return a, b, schema
+	}
+	return a.project(indexesA), b.project(indexesB), schema
+}
+
+// isIdentity reports whether indexes is 0, 1, 2, ...
+func isIdentity(indexes []int) bool {
+	for i, index := range indexes {
+		if index != i {
+			return false
+		}
+	}
+	return true
+}
+
+// columnType returns the static type of column i, or nil if unknown.
+func (ds dataset) columnType(i int) reflect.Type {
+	if i < len(ds.types) {
+		return ds.types[i]
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package datadiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAlignSchemas(t *testing.T) {
+	a := makeDataset("Person", []string{"ID", "Name", "Email"}, []any{1, "Alice", "a@example.com"})
+	b := makeDataset("PersonDTO", []string{"Name", "Phone", "ID"}, []any{"Alice", "555", 1})
+
+	gotA, gotB, schema := alignSchemas(a, b)
+
+	wantColumns := []string{"ID", "Name"}
+	if !reflect.DeepEqual(gotA.columns, wantColumns) || !reflect.DeepEqual(gotB.columns, wantColumns) {
+		t.Fatalf("columns mismatch: got %#v and %#v, want %#v", gotA.columns, gotB.columns, wantColumns)
+	}
+	if !reflect.DeepEqual(gotB.rows[0].values, []any{1, "Alice"}) {
+		t.Fatalf("expected listB values reordered to match listA, got %#v", gotB.rows[0].values)
+	}
+
+	want := schemaDiff{removed: []schemaColumn{{name: "Email"}}, added: []schemaColumn{{name: "Phone"}}}
+	if !reflect.DeepEqual(schema, want) {
+		t.Fatalf("schema mismatch: got %+v, want %+v", schema, want)
+	}
+}
+
+func TestAlignSchemas_SameColumns(t *testing.T) {
+	a := makePersonDataset([]any{"Alice", 30})
+	b := makePersonDataset([]any{"Bob", 25})
+
+	gotA, gotB, schema := alignSchemas(a, b)
+	if !schema.empty() {
+		t.Fatalf("expected no schema differences, got %+v", schema)
+	}
+	if !reflect.DeepEqual(gotA, a) || !reflect.DeepEqual(gotB, b) {
+		t.Fatal("expected datasets with equal columns to be returned unchanged")
+	}
+}
+
+func TestAlignSchemas_Retyped(t *testing.T) {
+	a := makePersonDataset([]any{"Alice", 30})
+	a.types = []reflect.Type{reflect.TypeFor[string](), reflect.TypeFor[int]()}
+	b := makePersonDataset([]any{"Alice", int64(30)})
+	b.types = []reflect.Type{reflect.TypeFor[string](), reflect.TypeFor[int64]()}
+
+	gotA, gotB, schema := alignSchemas(a, b)
+
+	want := schemaDiff{retyped: []schemaColumn{{name: "Age", typeA: reflect.TypeFor[int](), typeB: reflect.TypeFor[int64]()}}}
+	if !reflect.DeepEqual(schema, want) {
+		t.Fatalf("schema mismatch: got %+v, want %+v", schema, want)
+	}
+	if len(gotA.columns) != 2 || len(gotB.columns) != 2 {
+		t.Fatal("expected retyped columns to be kept for comparison")
+	}
+}
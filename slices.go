@@ -0,0 +1,71 @@
+package datadiff
+
+import "fmt"
+
+// SliceOption is an option for [AssertSlices] and [DiffSlices] typed by
+// the element type T, such as [Key] and [Compare]. Use [Flags] and [Opt]
+// to pass a [Flag] or an [Option].
+type SliceOption[T any] func(*options) error
+
+// AssertSlices is the type-safe form of [Assert]: want and got must have
+// the same element type, and every option must be typed by it, at
+// compile time.
+func AssertSlices[T any](t Reporter, want, got []T, opts ...SliceOption[T]) bool {
+	t.Helper()
+
+	return Assert(t, want, got, sliceFlags(opts)...)
+}
+
+// DiffSlices is the type-safe form of [Diff]; see [AssertSlices].
+func DiffSlices[T any](want, got []T, opts ...SliceOption[T]) (Result, error) {
+	return Diff(want, got, sliceFlags(opts)...)
+}
+
+// sliceFlags converts opts to options that [parseOptions] understands.
+func sliceFlags[T any](opts []SliceOption[T]) []any {
+	converted := make([]any, len(opts))
+	for i, option := range opts {
+		converted[i] = Option(option)
+	}
+	return converted
+}
+
+// Flags converts flags to a [SliceOption], as in
+// Flags[Person](IgnoreOrder, IgnoreLengths).
+func Flags[T any](flags ...Flag) SliceOption[T] {
+	return func(o *options) error {
+		for _, flag := range flags {
+			if err := o.applyFlag(flag); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// Opt converts an [Option] to a [SliceOption], as in
+// Opt[Person](WithTolerance(1e-9, 0)).
+func Opt[T any](o Option) SliceOption[T] {
+	return SliceOption[T](o)
+}
+
+// Key is the typed form of [WithKeyFunc] for [AssertSlices] and
+// [DiffSlices].
+func Key[T any, K comparable](fn func(T) K) SliceOption[T] {
+	return SliceOption[T](WithKeyFunc(fn))
+}
+
+// Compare sets fn as the equality function for whole elements, in place
+// of the column-by-column comparison. Columns that differ are still
+// highlighted in the diff output of rows fn reports as unequal.
+func Compare[T any](fn func(a, b T) bool) SliceOption[T] {
+	return func(o *options) error {
+		if fn == nil {
+			return fmt.Errorf("datadiff: Compare: nil function")
+		}
+		o.rowEqual = func(a, b any) bool {
+			return fn(a.(T), b.(T))
+		}
+		return nil
+	}
+}
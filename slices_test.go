@@ -0,0 +1,128 @@
+package datadiff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAssertSlices(t *testing.T) {
+	a := []Person{{Name: "Alice", Age: 30}, {Name: "Bob", Age: 25}}
+	b := []Person{{Name: "Bob", Age: 25}, {Name: "Alice", Age: 30}}
+
+	if !AssertSlices(t, a, b, Flags[Person](IgnoreOrder)) {
+		t.Fatal("expected AssertSlices to return true")
+	}
+
+	r := &fakeReporter{}
+	if AssertSlices(r, a, b) {
+		t.Fatal("expected AssertSlices to return false for reordered rows")
+	}
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "[]Person are not equal") {
+		t.Fatalf("expected one mismatch error, got %q", r.errors)
+	}
+}
+
+func TestDiffSlices_Key(t *testing.T) {
+	type User struct {
+		Email  string
+		Tenant int
+		Name   string
+	}
+	key := Key(func(u User) string {
+		return fmt.Sprintf("%s/%d", strings.ToLower(u.Email), u.Tenant)
+	})
+
+	a := []User{{Email: "Alice@example.com", Tenant: 1, Name: "Alice"}, {Email: "bob@example.com", Tenant: 1, Name: "Bob"}}
+	b := []User{{Email: "bob@example.com", Tenant: 1, Name: "Robert"}, {Email: "alice@example.com", Tenant: 1, Name: "Alice"}}
+
+	result, err := DiffSlices(a, b, key)
+	if err != nil {
+		t.Fatalf("DiffSlices returned unexpected error: %v", err)
+	}
+
+	rows := result.Rows()
+	if len(rows) != 2 {
+		t.Fatalf("row count mismatch: got %d, want %d", len(rows), 2)
+	}
+	// Email differs in case, so row 0 still mismatches on that column.
+	if rows[0].Key != "key=alice@example.com/1" || rows[0].Status != RowMismatch {
+		t.Fatalf("row 0 mismatch: got %+v", rows[0])
+	}
+	if rows[1].Key != "key=bob@example.com/1" || rows[1].Status != RowMismatch {
+		t.Fatalf("row 1 mismatch: got %+v", rows[1])
+	}
+	if !strings.Contains(result.String(), "key=bob@example.com/1") {
+		t.Fatalf("expected key label in output, got %q", result.String())
+	}
+}
+
+func TestDiffSlices_Compare(t *testing.T) {
+	sameName := Compare(func(a, b Person) bool {
+		return strings.EqualFold(a.Name, b.Name)
+	})
+
+	a := []Person{{Name: "Alice", Age: 30}, {Name: "Bob", Age: 25}}
+	b := []Person{{Name: "ALICE", Age: 31}, {Name: "Carol", Age: 25}}
+
+	result, err := DiffSlices(a, b, sameName)
+	if err != nil {
+		t.Fatalf("DiffSlices returned unexpected error: %v", err)
+	}
+
+	rows := result.Rows()
+	if rows[0].Status != RowMatch {
+		t.Fatalf("expected comparer to decide row 0 is equal, got %+v", rows[0])
+	}
+	if rows[1].Status != RowMismatch || !rows[1].Mismatch[0] || rows[1].Mismatch[1] {
+		t.Fatalf("expected row 1 to mismatch on Name only, got %+v", rows[1])
+	}
+
+	never := Compare(func(a, b Person) bool { return false })
+	result, err = DiffSlices(a[:1], a[:1], never)
+	if err != nil {
+		t.Fatalf("DiffSlices returned unexpected error: %v", err)
+	}
+	if got := result.Rows()[0].Mismatch; got[0] != true || got[1] != true {
+		t.Fatalf("expected every column flagged when no column differs, got %#v", got)
+	}
+}
+
+func TestDiffSlices_KeyWithNilRows(t *testing.T) {
+	key := Key(func(p *Person) string { return p.Name })
+
+	a := []*Person{{Name: "Alice", Age: 30}, nil}
+	b := []*Person{nil, {Name: "Alice", Age: 30}}
+
+	result, err := DiffSlices(a, b, key)
+	if err != nil {
+		t.Fatalf("DiffSlices returned unexpected error: %v", err)
+	}
+	if !result.Equal() {
+		t.Fatalf("expected rows to join by key, got:\n%s", result)
+	}
+}
+
+func TestDiffSlices_OptionErrors(t *testing.T) {
+	key := Key(func(p Person) string { return p.Name })
+
+	tests := []struct {
+		name    string
+		opts    []SliceOption[Person]
+		wantErr string
+	}{
+		{name: "key and key columns", opts: []SliceOption[Person]{key, Opt[Person](KeyColumns("Name"))}, wantErr: "a key function and KeyColumns cannot be combined"},
+		{name: "key and optimal", opts: []SliceOption[Person]{key, Flags[Person](IgnoreOrder, OptimalMatching)}, wantErr: "OptimalMatching has no effect when rows are matched by a key function"},
+		{name: "nil key", opts: []SliceOption[Person]{Key[Person, string](nil)}, wantErr: "WithKeyFunc: nil function"},
+		{name: "unknown flag", opts: []SliceOption[Person]{Flags[Person](Flag(99))}, wantErr: "unknown flag value: 99"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DiffSlices([]Person{}, []Person{}, tt.opts...)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error mismatch: got %v, want substring %q", err, tt.wantErr)
+			}
+		})
+	}
+}
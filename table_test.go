@@ -0,0 +1,65 @@
+package datadiff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want int
+	}{
+		{name: "ascii", s: "Alice", want: 5},
+		{name: "ansi", s: ansiRed + "31" + ansiReset, want: 2},
+		{name: "cjk", s: "東京", want: 4},
+		{name: "hangul", s: "서울", want: 4},
+		{name: "fullwidth", s: "ＡＢ", want: 4},
+		{name: "emoji", s: "🍣", want: 2},
+		{name: "variation selector", s: "☺\ufe0e", want: 1},
+		{name: "combining mark", s: "é", want: 1},
+		{name: "markers", s: "✓✗←→", want: 4},
+		{name: "truncated escape", s: "a\033[31", want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := displayWidth(tt.s); got != tt.want {
+				t.Fatalf("displayWidth(%q) = %d, want %d", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTable_Render(t *testing.T) {
+	var w table
+	w.add("#", "City", "Note")
+	w.add("0", ansiRed+"東京"+ansiReset, "← expected")
+	w.add("1", "Boston")
+
+	var b strings.Builder
+	w.render(&b)
+
+	want := "#  City    Note\n" +
+		"0  " + ansiRed + "東京" + ansiReset + "    ← expected\n" +
+		"1  Boston\n"
+	if b.String() != want {
+		t.Fatalf("render mismatch:\ngot  %q\nwant %q", b.String(), want)
+	}
+}
+
+func TestTable_AddLine(t *testing.T) {
+	var w table
+	w.add("#", "City")
+	w.addLine("… 1,024 matching rows …")
+	w.add("0", "Boston")
+
+	var b strings.Builder
+	w.render(&b)
+
+	want := "#  City\n… 1,024 matching rows …\n0  Boston\n"
+	if got := b.String(); got != want {
+		t.Fatalf("render mismatch:\ngot  %q\nwant %q", got, want)
+	}
+}
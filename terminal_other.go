@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !windows
+
+package datadiff
+
+import "os"
+
+// isTerminal reports whether f is a character device. Without a portable
+// terminal check on this platform, other character devices such as
+// /dev/null count as terminals too.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}